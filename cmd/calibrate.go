@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"github.com/twatzl/imtag/config"
+	"github.com/twatzl/imtag/tagger/image"
+	"github.com/twatzl/imtag/tagger/imageClassifier"
+	"io"
+	"io/ioutil"
+	"path"
+)
+
+// Calibrate fits the softmax temperature of the selected classifier on a validation folder. The
+// folder contains one subfolder per label of the classifier with images of that label. The subfolders
+// are named like the labels of the classifier or by the WordNet ids of the labels (e.g. n01440764).
+func Calibrate() {
+	logger := InitLogger(logrus.InfoLevel)
+	configValid, errors := config.VerifyConfigForCalibrate()
+
+	if !configValid {
+		for _, err := range errors {
+			logger.WithError(err).Errorln("invalid configuration value")
+		}
+		return
+	}
+
+	images, expectedLabels, err := loadValidationImages(config.GetValidationPath())
+	if err != nil {
+		logger.WithError(err).Errorln("could not read validation folder")
+		return
+	}
+
+	cd, err := config.GetClassifierDescription()
+	if err != nil {
+		logger.WithError(err).Errorln("could not get classifier description")
+		return
+	}
+
+	classifier, err := cd.InstantiateClassifier(logger)
+	if err != nil {
+		logger.WithError(err).Errorln("could not load image classifier")
+		return
+	}
+	if closer, ok := classifier.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	tags, err := classifier.ClassifyImages(images)
+	if err != nil {
+		logger.WithError(err).Errorln("error during classification")
+		return
+	}
+
+	probabilities := [][]float32{}
+	targets := []int{}
+	for i, imageTags := range tags {
+		if imageTags == nil {
+			logger.WithField("file", images[i].GetFilename()).WithError(images[i].GetError()).Warnln("skipping image")
+			continue
+		}
+
+		target := -1
+		values := make([]float32, len(imageTags))
+		for j, t := range imageTags {
+			values[j] = t.GetConfidence()
+			if t.GetLabel() == expectedLabels[i] || (t.GetSynsetId() != "" && t.GetSynsetId() == expectedLabels[i]) {
+				target = j
+			}
+		}
+		if target < 0 {
+			logger.WithField("label", expectedLabels[i]).Warnln("label is not known to the classifier, skipping image")
+			continue
+		}
+
+		probabilities = append(probabilities, values)
+		targets = append(targets, target)
+	}
+
+	temperature, nllBefore, nllAfter, err := imageClassifier.FitTemperature(probabilities, targets)
+	if err != nil {
+		logger.WithError(err).Errorln("could not fit temperature")
+		return
+	}
+
+	// the classifier already applied its configured temperature
+	currentTemperature := currentClassifierTemperature()
+
+	fmt.Printf("images:                   %d\n", len(targets))
+	fmt.Printf("negative log likelihood:  %f -> %f\n", nllBefore, nllAfter)
+	fmt.Printf("temperature:              %g\n", currentTemperature*temperature)
+}
+
+// loadValidationImages returns the images of all subfolders of the validation folder together with
+// the name of the subfolder, which is the expected label of the image.
+func loadValidationImages(dir string) (images []image.Image, labels []string, err error) {
+	folders, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, folder := range folders {
+		if !folder.IsDir() {
+			continue
+		}
+
+		files, err := ioutil.ReadDir(path.Join(dir, folder.Name()))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, file := range files {
+			if !file.Mode().IsRegular() {
+				continue
+			}
+			images = append(images, image.New(path.Join(dir, folder.Name(), file.Name())))
+			labels = append(labels, folder.Name())
+		}
+	}
+
+	return images, labels, nil
+}
+
+// currentClassifierTemperature returns the temperature of the selected classifier definition.
+func currentClassifierTemperature() float32 {
+	definitions, err := config.GetClassifierDefinitions()
+	if err != nil {
+		return 1
+	}
+
+	for _, def := range definitions {
+		if def.Name == config.GetClassifierName() && def.Temperature != 0 {
+			return def.Temperature
+		}
+	}
+	return 1
+}
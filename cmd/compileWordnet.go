@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/twatzl/imtag/config"
+	"github.com/twatzl/imtag/tagger/wordnetSnapshot"
+)
+
+func CompileWordNet() {
+	logger := InitLogger(logrus.DebugLevel)
+
+	ok, err := config.IsWordNetPathValid()
+	if !ok {
+		logger.WithError(err).Errorln("invalid configuration value")
+		return
+	}
+
+	dictPath := config.GetWordNetDictionaryPath()
+	snapshotPath := config.GetWordNetSnapshotPath()
+
+	logger.WithField("dict", dictPath).Infoln("compiling wordnet dictionary")
+	snapshot, err := wordnetSnapshot.Compile(dictPath)
+	if err != nil {
+		logger.WithError(err).Errorln("could not compile wordnet dictionary")
+		return
+	}
+
+	err = snapshot.Write(snapshotPath)
+	if err != nil {
+		logger.WithField("file", snapshotPath).WithError(err).Errorln("could not write wordnet snapshot")
+		return
+	}
+
+	logger.WithField("file", snapshotPath).
+		WithField("synsets", len(snapshot.WordNet.Synset)).
+		Infoln("wordnet snapshot written")
+}
@@ -24,13 +24,13 @@ func AddNewLabel() {
 	var wn *wordnet.WordNet
 	if config.HierarchicalEmbeddingEnabled() {
 		var err error
-		wn, err = LoadWordNet(config.GetWordNetDictionaryPath())
+		wn, err = LoadWordNet(logger, config.GetWordNetDictionaryPath(), config.GetWordNetSnapshotPath())
 		if err != nil {
 			logger.WithError(err).Errorln("could not load wordnet dictionary")
 		}
 	}
 
-	ls := tagger.NewFileLabelStorage(logger,"./labelstore")
+	ls := tagger.NewFileLabelStorage(logger, config.GetLabelStorePath())
 	err := ls.ReadFile()
 	if err != nil {
 		logger.Error("error during loading of label store")
@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"github.com/twatzl/imtag/config"
+	"github.com/twatzl/imtag/tagger/imageClassifier"
+	"github.com/twatzl/imtag/tagger/imageClassifier/tensorflowImageClassifier"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// InspectModel lists the candidates for the input and output tensors of a frozen graph or a saved
+// model and proposes a classifier definition for it.
+func InspectModel() {
+	logger := InitLogger(logrus.InfoLevel)
+	configValid, errors := config.VerifyConfigForInspectModel()
+
+	if !configValid {
+		for _, err := range errors {
+			logger.WithError(err).Errorln("invalid configuration value")
+		}
+		return
+	}
+
+	modelPath := config.GetModelPathForInspection()
+	info, err := os.Stat(modelPath)
+	if err != nil {
+		logger.WithError(err).Errorln("could not open model")
+		return
+	}
+
+	// saved models are directories, frozen graphs are files
+	var description *tensorflowImageClassifier.ModelDescription
+	if info.IsDir() {
+		description, err = tensorflowImageClassifier.InspectSavedModel(modelPath, config.GetGraphTags())
+	} else {
+		description, err = tensorflowImageClassifier.InspectFrozenModel(modelPath)
+	}
+	if err != nil {
+		logger.WithField("model", modelPath).WithError(err).Errorln("could not load model")
+		return
+	}
+
+	fmt.Println("inputs:")
+	printTensorDescriptions(description.Inputs)
+	fmt.Println("outputs:")
+	printTensorDescriptions(description.Outputs)
+
+	signatureNames := make([]string, 0, len(description.Signatures))
+	for name := range description.Signatures {
+		signatureNames = append(signatureNames, name)
+	}
+	sort.Strings(signatureNames)
+	for _, name := range signatureNames {
+		fmt.Printf("signature %s:\n", name)
+		fmt.Println("  inputs:")
+		printTensorDescriptions(description.Signatures[name].Inputs)
+		fmt.Println("  outputs:")
+		printTensorDescriptions(description.Signatures[name].Outputs)
+	}
+
+	fmt.Println()
+	fmt.Println("proposed classifier definition:")
+	printProposedDefinition(modelPath, info.IsDir(), description, signatureNames)
+}
+
+func printTensorDescriptions(tensors []tensorflowImageClassifier.TensorDescription) {
+	if len(tensors) == 0 {
+		fmt.Println("  none found")
+	}
+	for _, t := range tensors {
+		name := t.Name
+		if t.Tensor != "" {
+			name = fmt.Sprintf("%s (%s)", t.Name, t.Tensor)
+		}
+		fmt.Printf("  %-50s %-12s %s\n", name, t.Operation, formatShape(t.Shape))
+	}
+}
+
+// printProposedDefinition prints a classifier definition for the config file using the most likely
+// input and output. For saved models the keys of the serving signature are used.
+func printProposedDefinition(modelPath string, savedModel bool,
+	description *tensorflowImageClassifier.ModelDescription, signatureNames []string) {
+
+	inputs, outputs := description.Inputs, description.Outputs
+	backend := config.BackendTensorFlow
+	signatureName := ""
+	if savedModel {
+		backend = config.BackendTensorFlowSavedModel
+		if _, ok := description.Signatures["serving_default"]; ok {
+			signatureName = "serving_default"
+		} else if len(signatureNames) > 0 {
+			signatureName = signatureNames[0]
+		}
+		if signatureName != "" {
+			inputs = description.Signatures[signatureName].Inputs
+			outputs = description.Signatures[signatureName].Outputs
+		}
+	}
+
+	input, hasInput := bestInput(inputs)
+	output, hasOutput := bestOutput(outputs)
+
+	name := strings.TrimSuffix(path.Base(modelPath), path.Ext(modelPath))
+	fmt.Println("classifiers:")
+	fmt.Printf("  - name: %s\n", name)
+	fmt.Printf("    backend: %s\n", backend)
+	fmt.Printf("    modelPath: %s\n", modelPath)
+	if savedModel {
+		tags := config.GetGraphTags()
+		if len(tags) == 0 {
+			tags = []string{"serve"}
+		}
+		fmt.Printf("    tags: [%s]\n", strings.Join(tags, ", "))
+		if signatureName != "" {
+			fmt.Printf("    signature: %s\n", signatureName)
+		}
+	}
+	if hasInput {
+		fmt.Printf("    inputTag: %s\n", input.Name)
+	}
+	if hasOutput {
+		fmt.Printf("    outputTag: %s\n", output.Name)
+	}
+	fmt.Println("    labelFile: <label file>")
+
+	if hasOutput && len(output.Shape) > 0 && output.Shape[len(output.Shape)-1] > 0 {
+		numLabels := output.Shape[len(output.Shape)-1]
+		fmt.Printf("    numLabels: %d\n", numLabels)
+		if numLabels == 1000 {
+			fmt.Println("    labelOffset: 1                 # if the label file has a background line")
+		}
+	}
+
+	fmt.Println("    preprocessing: inception       # vgg, inception or resize, depending on the training")
+	if hasInput && len(input.Shape) == 4 && input.Shape[1] > 0 && input.Shape[2] > 0 {
+		fmt.Printf("    inputHeight: %d\n", input.Shape[1])
+		fmt.Printf("    inputWidth: %d\n", input.Shape[2])
+	}
+
+	if hasOutput {
+		outputType := imageClassifier.OutputLogits
+		if output.Probabilities {
+			outputType = imageClassifier.OutputProbabilities
+		}
+		fmt.Printf("    outputType: %s\n", outputType)
+	}
+}
+
+// bestInput prefers inputs with the shape of an image batch.
+func bestInput(inputs []tensorflowImageClassifier.TensorDescription) (tensorflowImageClassifier.TensorDescription, bool) {
+	for _, input := range inputs {
+		if len(input.Shape) == 4 {
+			return input, true
+		}
+	}
+	if len(inputs) > 0 {
+		return inputs[0], true
+	}
+	return tensorflowImageClassifier.TensorDescription{}, false
+}
+
+// bestOutput prefers outputs of shape [batch, classes] with the most classes, since the final layer
+// of a classifier is usually larger than auxiliary outputs.
+func bestOutput(outputs []tensorflowImageClassifier.TensorDescription) (tensorflowImageClassifier.TensorDescription, bool) {
+	best, found := tensorflowImageClassifier.TensorDescription{}, false
+	var bestSize int64 = -1
+	for _, output := range outputs {
+		if len(output.Shape) != 2 {
+			continue
+		}
+		if size := output.Shape[1]; size > bestSize {
+			best, bestSize, found = output, size, true
+		}
+	}
+
+	if !found && len(outputs) > 0 {
+		return outputs[0], true
+	}
+	return best, found
+}
+
+func formatShape(shape []int64) string {
+	if shape == nil {
+		return "unknown"
+	}
+
+	dims := make([]string, len(shape))
+	for i, d := range shape {
+		if d < 0 {
+			dims[i] = "?"
+		} else {
+			dims[i] = fmt.Sprintf("%d", d)
+		}
+	}
+	return "[" + strings.Join(dims, ", ") + "]"
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"github.com/twatzl/imtag/config"
+	"strings"
+)
+
+func ListClassifiers() {
+	logger := InitLogger(logrus.InfoLevel)
+
+	definitions, err := config.GetClassifierDefinitions()
+	if err != nil {
+		logger.WithError(err).Errorln("could not load classifier definitions")
+		return
+	}
+
+	for _, def := range definitions {
+		fmt.Printf("%s:\n", def.Name)
+		fmt.Printf("  backend:       %s\n", def.Backend)
+		if def.Backend == config.BackendSubprocess {
+			fmt.Printf("  command:       %s %s\n", def.Command, strings.Join(def.Args, " "))
+		} else if def.Backend == config.BackendEnsemble {
+			fmt.Printf("  members:       %s\n", strings.Join(def.Members, ", "))
+			if len(def.Weights) > 0 {
+				fmt.Printf("  weights:       %v\n", def.Weights)
+			}
+			if def.Merge != "" {
+				fmt.Printf("  merge:         %s\n", def.Merge)
+			}
+		} else if def.Backend == config.BackendRemote {
+			fmt.Printf("  url:           %s\n", def.URL)
+			if def.InputType != "" {
+				fmt.Printf("  input:         %s\n", def.InputType)
+			}
+		} else {
+			printModelDefinition(def)
+		}
+
+		if ok, errs := def.Verify(); !ok {
+			for _, err := range errs {
+				fmt.Printf("  invalid:       %s\n", err.Error())
+			}
+		}
+	}
+}
+
+func printModelDefinition(def config.ClassifierDefinition) {
+	if def.Backend == config.BackendTFServing {
+		fmt.Printf("  url:           %s\n", def.URL)
+		fmt.Printf("  model:         %s (version %d)\n", def.ModelName, def.ModelVersion)
+	} else {
+		fmt.Printf("  model:         %s\n", def.ModelPath)
+	}
+	if def.Backend == config.BackendTensorFlowSavedModel {
+		fmt.Printf("  tags:          %v\n", def.Tags)
+		fmt.Printf("  signature:     %s\n", def.Signature)
+	}
+	fmt.Printf("  input/output:  %s -> %s\n", def.InputTag, def.OutputTag)
+	if def.FeatureTag != "" {
+		fmt.Printf("  features:      %s\n", def.FeatureTag)
+	}
+	if def.LabelOffset != 0 {
+		fmt.Printf("  labels:        %s (%d, offset %d)\n", def.LabelFile, def.NumLabels, def.LabelOffset)
+	} else {
+		fmt.Printf("  labels:        %s (%d)\n", def.LabelFile, def.NumLabels)
+	}
+	if def.WnidLabelFile != "" {
+		fmt.Printf("  synsets:       %s\n", def.WnidLabelFile)
+	}
+	fmt.Printf("  preprocessing: %s %dx%d\n", def.Preprocessing, def.InputHeight, def.InputWidth)
+	fmt.Printf("  output:        %s\n", def.OutputType)
+	if def.Temperature != 0 {
+		fmt.Printf("  temperature:   %g\n", def.Temperature)
+	}
+	if def.Augmentation != "" {
+		fmt.Printf("  augmentation:  %s\n", def.Augmentation)
+	}
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/twatzl/imtag/config"
+	"github.com/twatzl/imtag/tagger"
+	"github.com/twatzl/imtag/tagger/wordnetMapping"
+)
+
+func MigrateLabels() {
+	logger := InitLogger(logrus.DebugLevel)
+	configValid, errors := config.VerifyConfigForMigrateLabels()
+
+	if !configValid {
+		for _, err := range errors {
+			logger.WithError(err).Errorln("invalid configuration value")
+		}
+		return
+	}
+
+	var mapping wordnetMapping.Mapping
+	var err error
+	if mappingFile := config.GetWordNetMappingFilePath(); mappingFile != "" {
+		logger.WithField("file", mappingFile).Infoln("loading wordnet mapping file")
+		mapping, err = wordnetMapping.NewFromFile(mappingFile)
+	} else {
+		logger.WithField("source", config.GetSourceWordNetDictionaryPath()).
+			WithField("target", config.GetWordNetDictionaryPath()).
+			Infoln("creating wordnet mapping from sense indices")
+		mapping, err = wordnetMapping.NewFromSenseIndex(config.GetSourceWordNetDictionaryPath(), config.GetWordNetDictionaryPath())
+	}
+	if err != nil {
+		logger.WithError(err).Errorln("could not create wordnet mapping")
+		return
+	}
+
+	source := tagger.NewFileLabelStorage(logger, config.GetLabelStorePath())
+	err = source.ReadFile()
+	if err != nil {
+		logger.Error("error during loading of label store")
+		return
+	}
+
+	target := tagger.NewFileLabelStorage(logger, config.GetOutputLabelStorePath())
+	unmapped, err := tagger.MigrateLabels(source, target, mapping)
+	if err != nil {
+		logger.WithError(err).Errorln("error during migration of labels")
+		return
+	}
+
+	for _, l := range unmapped {
+		logger.WithField("synsetid", l).Warnln("label could not be mapped and will be dropped")
+	}
+
+	err = target.WriteFile()
+	if err != nil {
+		logger.Error("error during writing of label store")
+		return
+	}
+
+	migrated, _ := target.LoadLabelsSlice()
+	logger.WithField("migrated", len(migrated)).
+		WithField("unmapped", len(unmapped)).
+		WithField("file", config.GetOutputLabelStorePath()).
+		Infoln("label store migrated")
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/twatzl/imtag/config"
+	"github.com/twatzl/imtag/tagger"
+	"github.com/twatzl/imtag/tagger/knn"
+	"github.com/twatzl/imtag/tagger/label"
+	"github.com/twatzl/imtag/tagger/word2vec"
+	"regexp"
+)
+
+func Neighbors() {
+	logger := InitLogger(logrus.DebugLevel)
+	searchTerm := viper.GetString(config.FlagLabelKeyForNeighbors)
+	numNeighbors := config.GetNumNeighbors()
+
+	configValid, errors := config.VerifyConfigForNeighbors()
+
+	if !configValid {
+		for _, err := range errors {
+			logger.WithError(err).Errorln("invalid configuration value")
+		}
+		return
+	}
+
+	w2v, err := LoadWord2VecModel(config.GetWord2VecModelPath())
+	if err != nil {
+		logger.WithError(err).Errorln("could not load word2vec model")
+		return
+	}
+
+	// words of the search term itself are not interesting as neighbors
+	exclude := map[string]bool{searchTerm: true}
+	var vec []float32
+
+	isSynsetId, _ := regexp.MatchString("^n[0-9]{8}$", searchTerm)
+	if isSynsetId {
+		wn, err := LoadWordNet(logger, config.GetWordNetDictionaryPath(), config.GetWordNetSnapshotPath())
+		if err != nil {
+			logger.WithError(err).Errorln("could not load wordnet dictionary")
+			return
+		}
+
+		synset, ok := wn.Synset[searchTerm]
+		if !ok {
+			logger.WithField("synsetid", searchTerm).Errorln("synset not found in wordnet")
+			return
+		}
+
+		for _, word := range synset.Word {
+			exclude[word] = true
+		}
+		vec = tagger.EmbedSynset(w2v, synset)
+	} else {
+		vec = w2v.Word2Vec(searchTerm)
+	}
+
+	if vec == nil {
+		logger.WithField("label", searchTerm).Errorln("label not known to word2vec implementation")
+		return
+	}
+
+	logger.WithField("words", len(w2v.Vocabulary())).Infoln("searching nearest words in vocabulary")
+	neighbors, err := nearestWords(w2v, vec, numNeighbors, exclude)
+	if err != nil {
+		logger.WithError(err).Errorln("error while reading word2vec vocabulary")
+		return
+	}
+
+	fmt.Printf("nearest words to %s:\n", searchTerm)
+	for _, n := range neighbors {
+		fmt.Printf("%-30s %f\n", n.Label.GetLabel(), 1-n.Distance)
+	}
+}
+
+// nearestWords returns the n words of the word2vec vocabulary which have the highest cosine
+// similarity to the given vector. Words in exclude are skipped.
+func nearestWords(w2v word2vec.Word2Vec, vec []float32, n int, exclude map[string]bool) ([]knn.Neighbor, error) {
+	vocabulary := make([]label.Label, 0, len(w2v.Vocabulary()))
+	err := w2v.ForEachWord(func(word string, vector []float32) error {
+		if !exclude[word] {
+			vocabulary = append(vocabulary, label.New(word, vector))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return knn.KnnSearchWithDistances(vocabulary, [][]float32{vec}, n, knn.CosDist)[0], nil
+}
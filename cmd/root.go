@@ -48,12 +48,95 @@ var tagCmd = &cobra.Command{
 var searchLabelCmd = &cobra.Command{
 	Use: "search",
 	Short: "Search if a label is known to wordnet and w2v.",
-	Long: "search will look in wordnet and word2vec model if the given label is known.",
+	Long: `search will look in wordnet and word2vec model if the given label is known. For every noun sense
+the gloss, the words, the hypernyms up to the root and the nearest registered labels are shown. If the label
+is unknown similar words are suggested.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		SearchLabel()
 	},
 }
 
+var migrateLabelsCmd = &cobra.Command{
+	Use:   "migrateLabels",
+	Short: "Migrate a label store to another WordNet version.",
+	Long: `migrateLabels will translate the synset ids in a label store from one WordNet version to another
+(e.g. from 3.0 to 3.1). The mapping is either derived from the sense indices of the source and target
+dictionary or loaded from a mapping file. Labels which cannot be mapped are reported and dropped.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		MigrateLabels()
+	},
+}
+
+var compileWordNetCmd = &cobra.Command{
+	Use:   "compileWordnet",
+	Short: "Precompile the WordNet dictionary for faster startup.",
+	Long: `compileWordnet will parse the WordNet dictionary once and store the synsets, the lemma index and the
+hypernym closures in a binary snapshot. All other commands load the snapshot instead of the dictionary as long as
+the dictionary has not changed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		CompileWordNet()
+	},
+}
+
+var neighborsCmd = &cobra.Command{
+	Use:   "neighbors",
+	Short: "List the most similar words in the word2vec model.",
+	Long: `neighbors will list the words of the word2vec vocabulary which are most similar to the given word or
+synset (by cosine similarity). This helps to understand why labels get confused and to choose good words for
+custom labels.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		Neighbors()
+	},
+}
+
+var listClassifiersCmd = &cobra.Command{
+	Use:   "listClassifiers",
+	Short: "List the classifiers which can be used for tagging.",
+	Long: `listClassifiers will list the built-in classifiers and the ones defined in the classifiers section of
+the config file together with their backend, model, tensor names, labels and preprocessing.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ListClassifiers()
+	},
+}
+
+var calibrateCmd = &cobra.Command{
+	Use:   "calibrate",
+	Short: "Fit the softmax temperature of a classifier.",
+	Long: `calibrate will classify the images of a validation folder and fit the temperature of the softmax so the
+probabilities of the classifier match its accuracy. The folder must contain one subfolder per label of the classifier
+with images of that label. The subfolders are named like the labels of the classifier (e.g. "tench") or by their
+WordNet ids (e.g. n01440764), as in the ImageNet validation set. The resulting temperature can be set in the
+classifier definition.`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		// the classifier flags are shared with the tag command, so they are bound when the command runs
+		err := viper.BindPFlags(cmd.Flags())
+		if err != nil {
+			logrus.WithError(err).Errorln("could not bind flags for calibrate cmd")
+		}
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		Calibrate()
+	},
+}
+
+var inspectModelCmd = &cobra.Command{
+	Use:   "inspectModel",
+	Short: "List the inputs and outputs of a TensorFlow model.",
+	Long: `inspectModel will load a frozen graph or a saved model and list the placeholders which can be used as
+input and the operations which are likely outputs of the classifier together with their shapes. For saved models
+the signatures are listed as well. Finally a classifier definition for the config file is proposed.`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		// the classifier path flag is shared with the tag command, so the flags are bound when the command runs
+		err := viper.BindPFlags(cmd.Flags())
+		if err != nil {
+			logrus.WithError(err).Errorln("could not bind flags for inspect model cmd")
+		}
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		InspectModel()
+	},
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
@@ -77,9 +160,15 @@ func init() {
 	rootCmd.PersistentFlags().String(config.FlagWordNetDictionary,
 		viper.GetString(config.FlagWordNetDictionary),
 		"The wordnet dictionary to be used.")
+	rootCmd.PersistentFlags().String(config.FlagWordNetSnapshot,
+		viper.GetString(config.FlagWordNetSnapshot),
+		"The precompiled wordnet snapshot (default is the wordnet dictionary path with .snapshot suffix).")
 	rootCmd.PersistentFlags().String(config.FlagDataPath,
 		viper.GetString(config.FlagDataPath),
 		"The path to where the data for the application is stored (i.e. mod els etc.)")
+	rootCmd.PersistentFlags().String(config.FlagLabelStore,
+		viper.GetString(config.FlagLabelStore),
+		"The file in which the registered labels are stored.")
 
 	err := viper.BindPFlags(rootCmd.PersistentFlags())
 	if err != nil {
@@ -95,7 +184,7 @@ func init() {
 		logrus.WithError(err).Errorln("could not bind flags for add label cmd")
 	}
 
-	searchLabelCmd.Flags().StringP(config.FlagLabel, "l", "", "The label to search for.")
+	searchLabelCmd.Flags().StringP(config.FlagLabel, "l", "", "The label to search for. Label can be either a word or wordnet id.")
 
 	// this is a workaround since using the same key in multiple commands leads to issues with lookup in viper
 	err = viper.BindPFlag(config.FlagLabelKeyForSearch, searchLabelCmd.Flags().Lookup(config.FlagLabel))
@@ -103,6 +192,42 @@ func init() {
 		logrus.WithError(err).Errorln("could not bind flags for search label cmd")
 	}
 
+	neighborsCmd.Flags().StringP(config.FlagLabel, "l", "", "The word or wordnet id for which the neighbors are searched.")
+	neighborsCmd.Flags().IntP(
+		config.FlagNumNeighbors,
+		"n",
+		viper.GetInt(config.FlagNumNeighbors),
+		"The number of similar words to display.")
+
+	// same workaround as for search label cmd
+	err = viper.BindPFlag(config.FlagLabelKeyForNeighbors, neighborsCmd.Flags().Lookup(config.FlagLabel))
+	if err != nil {
+		logrus.WithError(err).Errorln("could not bind flags for neighbors cmd")
+	}
+	err = viper.BindPFlag(config.FlagNumNeighbors, neighborsCmd.Flags().Lookup(config.FlagNumNeighbors))
+	if err != nil {
+		logrus.WithError(err).Errorln("could not bind flags for neighbors cmd")
+	}
+
+	// parameters for migrating labels between wordnet versions
+	migrateLabelsCmd.Flags().String(
+		config.FlagSourceWordNetDictionary,
+		"",
+		"The wordnet dictionary of the version the label store currently uses. The target version is given by the wordnet flag.")
+	migrateLabelsCmd.Flags().String(
+		config.FlagWordNetMappingFile,
+		"",
+		"A file mapping source synset ids to target synset ids (one pair per line). Used instead of the sense indices if set.")
+	migrateLabelsCmd.Flags().StringP(
+		config.FlagOutputLabelStore,
+		"o",
+		"",
+		"The file the migrated label store is written to. If not set the label store is migrated in place.")
+	err = viper.BindPFlags(migrateLabelsCmd.Flags())
+	if err != nil {
+		logrus.WithError(err).Errorln("could not bind flags for migrate labels cmd")
+	}
+
 	// parameters for tagging
 	tagCmd.Flags().StringP(
 		config.FlagClassifierName,
@@ -118,6 +243,15 @@ func init() {
 		"f",
 		"",
 		"The image file to tag")
+	tagCmd.Flags().Int(
+		config.FlagBatchSize,
+		viper.GetInt(config.FlagBatchSize),
+		"The number of images which are classified at once.")
+	tagCmd.Flags().String(
+		config.FlagAugmentation,
+		"",
+		"Classify several crops of each image and average the results (none, fiveCrop or tenCrop). "+
+			"Overrides the augmentation of the classifier.")
 	tagCmd.Flags().IntP(
 		config.FlagK,
 		"k",
@@ -137,14 +271,83 @@ func init() {
 		config.FlagRawClassifierResults,
 		viper.GetBool(config.FlagRawClassifierResults),
 		"If this flag is set the raw results from classifier p0 will be printed instead of zero shot tagging.")
+	tagCmd.Flags().Int(
+		config.FlagTiles,
+		viper.GetInt(config.FlagTiles),
+		"Split each image into a grid of n x n regions which are tagged in addition to the whole image. "+
+			"The tags of the regions are merged into the tags of the image. 0 disables the tiling.")
+	tagCmd.Flags().Float64(
+		config.FlagTileOverlap,
+		viper.GetFloat64(config.FlagTileOverlap),
+		"The fraction by which neighbouring regions overlap.")
+	tagCmd.Flags().Bool(
+		config.FlagJSONOutput,
+		false,
+		"Print the results as JSON.")
+	tagCmd.Flags().Bool(
+		config.FlagRegionBoxes,
+		false,
+		"Print the bounds and tags of each region in addition to the merged tags of the image.")
+	tagCmd.Flags().Int(
+		config.FlagFrames,
+		viper.GetInt(config.FlagFrames),
+		"The number of frames which are sampled from animated GIFs and sequences.")
+	tagCmd.Flags().String(
+		config.FlagSequenceMerge,
+		viper.GetString(config.FlagSequenceMerge),
+		"How the predictions of the frames of a sequence are combined (mean or max).")
+	tagCmd.Flags().Bool(
+		config.FlagFrameDetail,
+		false,
+		"Print the tags of each sampled frame in addition to the tags of the sequence.")
 	err = viper.BindPFlags(tagCmd.Flags())
 	if err != nil {
 		logrus.WithError(err).Errorln("could not bind flags for tagging cmd")
 	}
 
+	// parameters for calibration
+	calibrateCmd.Flags().StringP(
+		config.FlagClassifierName,
+		"c",
+		viper.GetString(config.FlagClassifierName),
+		fmt.Sprintf("The classifier to calibrate. Allowed values: %s", config.GetKnownClassifierNames()))
+	calibrateCmd.Flags().String(
+		config.FlagClassifierPath,
+		viper.GetString(config.FlagClassifierPath),
+		"The path where the TensorFlow classifiers are stored.")
+	calibrateCmd.Flags().String(
+		config.FlagValidationPath,
+		"",
+		"The folder with one subfolder of images per label of the classifier.")
+	calibrateCmd.Flags().Int(
+		config.FlagBatchSize,
+		viper.GetInt(config.FlagBatchSize),
+		"The number of images which are classified at once.")
+
+	// parameters for inspecting models
+	inspectModelCmd.Flags().StringP(
+		config.FlagModel,
+		"m",
+		"",
+		"The frozen graph or saved model directory to inspect. Relative paths are also searched in the classifier path.")
+	inspectModelCmd.Flags().String(
+		config.FlagClassifierPath,
+		viper.GetString(config.FlagClassifierPath),
+		"The path where the TensorFlow classifiers are stored.")
+	inspectModelCmd.Flags().StringSlice(
+		config.FlagGraphTags,
+		nil,
+		"The tag set with which a saved model is loaded (default is serve).")
+
 	rootCmd.AddCommand(addLabelCmd)
 	rootCmd.AddCommand(searchLabelCmd)
 	rootCmd.AddCommand(tagCmd)
+	rootCmd.AddCommand(migrateLabelsCmd)
+	rootCmd.AddCommand(compileWordNetCmd)
+	rootCmd.AddCommand(neighborsCmd)
+	rootCmd.AddCommand(listClassifiersCmd)
+	rootCmd.AddCommand(calibrateCmd)
+	rootCmd.AddCommand(inspectModelCmd)
 }
 
 // initConfig reads in config file and ENV variables if set.
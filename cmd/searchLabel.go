@@ -1,17 +1,29 @@
 package cmd
 
 import (
+	"fmt"
 	"github.com/fluhus/gostuff/nlp/wordnet"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 	"github.com/twatzl/imtag/config"
+	"github.com/twatzl/imtag/tagger"
+	"github.com/twatzl/imtag/tagger/knn"
+	"github.com/twatzl/imtag/tagger/label"
+	"github.com/twatzl/imtag/tagger/suggest"
+	"github.com/twatzl/imtag/tagger/word2vec"
+	"regexp"
+	"strings"
 )
 
+const numNearestLabels = 5
+const numSuggestions = 5
+const maxSuggestionDistance = 2
+
 func SearchLabel() {
 	logger := InitLogger(logrus.DebugLevel)
-	label := viper.GetString(config.FlagLabelKeyForSearch)
+	searchTerm := viper.GetString(config.FlagLabelKeyForSearch)
 
-	configValid, errors := config.VerifyConfigForEmbedLabel()
+	configValid, errors := config.VerifyConfigForSearchLabel()
 
 	if !configValid {
 		for _, err := range errors {
@@ -20,7 +32,7 @@ func SearchLabel() {
 		return
 	}
 
-	logger.WithField("label", label).Infoln("looking for label in word2vec and wordnet")
+	logger.WithField("label", searchTerm).Infoln("looking for label in word2vec and wordnet")
 
 	w2v, err := LoadWord2VecModel(config.GetWord2VecModelPath())
 	if err != nil {
@@ -28,26 +40,134 @@ func SearchLabel() {
 		return
 	}
 
-	var wn *wordnet.WordNet
-	if config.HierarchicalEmbeddingEnabled() {
-		wn, err = LoadWordNet(config.GetWordNetDictionaryPath())
-		if err != nil {
-			logger.WithError(err).Errorln("could not load wordnet dictionary")
-		}
+	wn, err := LoadWordNet(logger, config.GetWordNetDictionaryPath(), config.GetWordNetSnapshotPath())
+	if err != nil {
+		logger.WithError(err).Errorln("could not load wordnet dictionary")
+		return
 	}
 
-	vec := w2v.Word2Vec(label)
-	if vec == nil {
+	if !w2v.Contains(searchTerm) {
 		logger.Warnln("label not known to word2vec implementation")
 	} else {
 		logger.Infoln("label found in word2vec")
 	}
 
-	synset := wn.Search(label)["n"]
-	if synset == nil {
+	synsets := searchNounSynsets(wn, searchTerm)
+	if len(synsets) == 0 {
 		logger.Warnln("label is not in wordnet")
-	} else {
-		logger.WithField("synsetid", synset[0].Id()).WithField("synsetdesc", synset[0].String()).Infoln("label found in wordnet")
+		printSuggestions(wn, searchTerm)
+		return
+	}
+
+	ls := tagger.NewFileLabelStorage(logger, config.GetLabelStorePath())
+	err = ls.ReadFile()
+	if err != nil {
+		logger.WithError(err).Errorln("error during loading of label store")
+		return
+	}
+
+	registeredLabels, err := ls.LoadLabelsSlice()
+	if err != nil {
+		logger.WithError(err).Errorln("could not load registered labels")
+		return
+	}
+	embeddedLabels := embedRegisteredLabels(w2v, wn, registeredLabels)
+
+	fmt.Printf("%d noun sense(s) found for %s:\n", len(synsets), searchTerm)
+	for i, s := range synsets {
+		fmt.Println()
+		printSense(i+1, w2v, wn, s)
+		printNearestLabels(w2v, wn, s, embeddedLabels)
+	}
+}
+
+// searchNounSynsets returns the synset if the search term is a synset id, otherwise all
+// noun synsets containing the search term.
+func searchNounSynsets(wn *wordnet.WordNet, searchTerm string) []*wordnet.Synset {
+	matched, _ := regexp.MatchString("^n[0-9]{8}$", searchTerm)
+	if matched {
+		if synset, ok := wn.Synset[searchTerm]; ok {
+			return []*wordnet.Synset{synset}
+		}
+		return nil
+	}
+
+	// "n" = search for nouns
+	return wn.Search(searchTerm)["n"]
+}
+
+func printSense(number int, w2v word2vec.Word2Vec, wn *wordnet.WordNet, synset *wordnet.Synset) {
+	fmt.Printf("%d. %s: %s\n", number, synset.Id(), strings.Join(synset.Word, ", "))
+	fmt.Printf("   gloss:     %s\n", synset.Gloss)
+
+	hypernyms := []string{}
+	for _, s := range tagger.HypernymPath(wn, synset)[1:] {
+		hypernyms = append(hypernyms, s.Word[0])
+	}
+	fmt.Printf("   hypernyms: %s\n", strings.Join(hypernyms, " > "))
+
+	availability := []string{}
+	for _, word := range synset.Word {
+		known := "no"
+		if w2v.Contains(word) {
+			known = "yes"
+		}
+		availability = append(availability, fmt.Sprintf("%s (%s)", word, known))
+	}
+	fmt.Printf("   word2vec:  %s\n", strings.Join(availability, ", "))
+}
+
+func printNearestLabels(w2v word2vec.Word2Vec, wn *wordnet.WordNet, synset *wordnet.Synset, embeddedLabels []label.Label) {
+	if len(embeddedLabels) == 0 {
+		return
+	}
+
+	vec := tagger.EmbedSynset(w2v, synset)
+	if vec == nil {
+		fmt.Println("   nearest registered labels: none of the words is known to word2vec")
+		return
+	}
+
+	neighbors := knn.KnnSearchWithDistances(embeddedLabels, [][]float32{vec}, numNearestLabels, knn.CosDist)[0]
+
+	fmt.Println("   nearest registered labels:")
+	for _, n := range neighbors {
+		synsetId := n.Label.GetLabel()
+		fmt.Printf("     %s %-30s similarity %f\n", synsetId, strings.Join(wn.Synset[synsetId].Word, ", "), 1-n.Distance)
+	}
+}
+
+// embedRegisteredLabels embeds all registered labels which are known to wordnet and word2vec.
+func embedRegisteredLabels(w2v word2vec.Word2Vec, wn *wordnet.WordNet, labels []string) []label.Label {
+	embeddedLabels := []label.Label{}
+	for _, l := range labels {
+		synset, ok := wn.Synset[l]
+		if !ok {
+			continue
+		}
+
+		vec := tagger.EmbedSynset(w2v, synset)
+		if vec == nil {
+			continue
+		}
+
+		embeddedLabels = append(embeddedLabels, label.New(l, vec))
+	}
+	return embeddedLabels
+}
+
+func printSuggestions(wn *wordnet.WordNet, searchTerm string) {
+	vocabulary := []string{}
+	for _, s := range wn.Synset {
+		if s.Pos == "n" {
+			vocabulary = append(vocabulary, s.Word...)
+		}
+	}
+
+	suggestions := suggest.Suggest(searchTerm, vocabulary, numSuggestions, maxSuggestionDistance)
+	if len(suggestions) == 0 {
+		return
 	}
 
+	fmt.Printf("did you mean: %s\n", strings.Join(suggestions, ", "))
 }
@@ -1,10 +1,16 @@
 package cmd
 
 import (
+	"context"
+	"fmt"
 	"github.com/fluhus/gostuff/nlp/wordnet"
 	"github.com/sirupsen/logrus"
 	"github.com/twatzl/imtag/config"
 	"github.com/twatzl/imtag/tagger"
+	"github.com/twatzl/imtag/tagger/image"
+	"io"
+	"os"
+	"os/signal"
 )
 
 func TagImage() {
@@ -26,10 +32,16 @@ func TagImage() {
 
 	var wn *wordnet.WordNet
 	if config.HierarchicalEmbeddingEnabled() {
-		wn, err = LoadWordNet(config.GetWordNetDictionaryPath())
+		wn, err = LoadWordNet(logger, config.GetWordNetDictionaryPath(), config.GetWordNetSnapshotPath())
 		if err != nil {
 			logger.WithError(err).Errorln("could not load wordnet dictionary")
 		}
+	} else if ok, _ := config.IsWordNetPathValid(); ok {
+		// the labels of the classifier are embedded by the words of their synsets if they have one
+		wn, err = LoadWordNet(logger, config.GetWordNetDictionaryPath(), config.GetWordNetSnapshotPath())
+		if err != nil {
+			logger.WithError(err).Warnln("could not load wordnet dictionary, labels are embedded by their names")
+		}
 	}
 
 	cd, err := config.GetClassifierDescription()
@@ -43,14 +55,59 @@ func TagImage() {
 		logger.WithError(err).Errorln("could not load image classifier")
 		return
 	}
+	if closer, ok := classifier.(io.Closer); ok {
+		defer closer.Close()
+	}
 
-	ls := tagger.NewFileLabelStorage(logger,"./labelstore")
+	ls := tagger.NewFileLabelStorage(logger, config.GetLabelStorePath())
 	tc := NewTaggerConfig(w2v, wn, classifier, ls)
 	t := tagger.New(tc, logger)
 
-	taggedImages, err := t.LoadAndTagImages(config.GetPathToImageFiles())
+	images, err := t.LoadImages(config.GetPathToImageFiles())
+	if err != nil {
+		logger.WithError(err).Errorln("could not load images")
+		return
+	}
+
+	// the first interrupt stops the tagging after the current batch, a second one exits immediately
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	go func() {
+		select {
+		case <-interrupt:
+			logger.Warnln("interrupted, stopping after the current batch")
+			signal.Stop(interrupt)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	results, err := t.TagImages(ctx, tagger.ImageSource(ctx, images))
 	if err != nil {
-		// TODO error
+		logger.WithError(err).Errorln("could not start tagging")
+		return
+	}
+
+	// text results are printed as soon as they are available, json is printed as a whole
+	taggedImages := make([]image.Image, 0, len(images))
+	for result := range results {
+		taggedImages = append(taggedImages, result.Image)
+		logger.WithField("file", result.Image.GetFilename()).
+			WithField("progress", fmt.Sprintf("%d/%d", len(taggedImages), len(images))).Infoln("tagged image")
+		if !config.JSONOutputEnabled() {
+			PrintImageResults(result.Image, config.RegionBoxesEnabled(), config.FrameDetailEnabled())
+		}
+	}
+	if ctx.Err() != nil {
+		logger.WithField("tagged", len(taggedImages)).WithField("images", len(images)).Warnln("tagging was canceled")
+	}
+
+	if config.JSONOutputEnabled() {
+		if err := PrintResultsJSON(taggedImages, config.RegionBoxesEnabled(), config.FrameDetailEnabled()); err != nil {
+			logger.WithError(err).Errorln("could not print results")
+		}
 	}
-	PrintResults(taggedImages)
 }
\ No newline at end of file
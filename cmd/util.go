@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/fluhus/gostuff/nlp/wordnet"
 	log "github.com/sirupsen/logrus"
@@ -11,6 +12,7 @@ import (
 	"github.com/twatzl/imtag/tagger/tag"
 	"github.com/twatzl/imtag/tagger/word2vec"
 	"github.com/twatzl/imtag/tagger/word2vec/skipGramModel"
+	"github.com/twatzl/imtag/tagger/wordnetSnapshot"
 	"os"
 	"runtime"
 	"strings"
@@ -45,6 +47,11 @@ func NewTaggerConfig(w2v word2vec.Word2Vec,
 		Confidence:           config.GetConfidence(),
 		EmbedHierarchical:    config.HierarchicalEmbeddingEnabled(),
 		RawClassifierResults: config.RawClassifierResultsEnabled(),
+		Tiles:                config.GetTiles(),
+		TileOverlap:          config.GetTileOverlap(),
+		MaxFrames:            config.GetMaxFrames(),
+		SequenceMerge:        config.GetSequenceMerge(),
+		BatchSize:            config.GetBatchSize(),
 		Word2VecModel:        w2v,
 		WordNet:              wordnet,
 		ImageClassifier:      classifier,
@@ -53,23 +60,129 @@ func NewTaggerConfig(w2v word2vec.Word2Vec,
 	return conf
 }
 
-func PrintResults(images []image.Image) {
+func PrintResults(images []image.Image, withRegions bool, withFrames bool) {
 	for _, i := range images {
-		PrintImageResults(i)
+		PrintImageResults(i, withRegions, withFrames)
 	}
 }
 
-func PrintImageResults(i image.Image) {
+func PrintImageResults(i image.Image, withRegions bool, withFrames bool) {
 	tags := i.GetTags()
 
 	fmt.Printf("%s:\n", i.GetFilename())
 
+	if err := i.GetError(); err != nil {
+		fmt.Printf("error: %s\n", err.Error())
+		return
+	}
+
 	for _, ta := range tags {
 		PrintTag(ta)
 	}
+
+	if withRegions {
+		for _, r := range i.GetRegions() {
+			fmt.Printf("region %d,%d %dx%d:\n", r.X, r.Y, r.Width, r.Height)
+			for _, ta := range r.Tags {
+				fmt.Print("  ")
+				PrintTag(ta)
+			}
+		}
+	}
+
+	if withFrames {
+		for _, f := range i.GetFrames() {
+			fmt.Printf("frame %d:\n", f.Index)
+			if err := f.Image.GetError(); err != nil {
+				fmt.Printf("  error: %s\n", err.Error())
+				continue
+			}
+			for _, ta := range f.Image.GetTags() {
+				fmt.Print("  ")
+				PrintTag(ta)
+			}
+		}
+	}
+}
+
+type jsonTag struct {
+	Label      string  `json:"label"`
+	Synset     string  `json:"synset,omitempty"`
+	Confidence float32 `json:"confidence"`
+}
+
+type jsonRegion struct {
+	X      int       `json:"x"`
+	Y      int       `json:"y"`
+	Width  int       `json:"width"`
+	Height int       `json:"height"`
+	Tags   []jsonTag `json:"tags"`
+}
+
+type jsonFrame struct {
+	Index int       `json:"index"`
+	Error string    `json:"error,omitempty"`
+	Tags  []jsonTag `json:"tags"`
+}
+
+type jsonImageResult struct {
+	File    string       `json:"file"`
+	Error   string       `json:"error,omitempty"`
+	Tags    []jsonTag    `json:"tags"`
+	Regions []jsonRegion `json:"regions,omitempty"`
+	Frames  []jsonFrame  `json:"frames,omitempty"`
+}
+
+// PrintResultsJSON prints the results as a JSON array with one object per image. The regions and
+// the frames of sequences are only included if withRegions or withFrames is set.
+func PrintResultsJSON(images []image.Image, withRegions bool, withFrames bool) error {
+	results := make([]jsonImageResult, 0, len(images))
+	for _, i := range images {
+		result := jsonImageResult{File: i.GetFilename(), Tags: toJSONTags(i.GetTags())}
+		if err := i.GetError(); err != nil {
+			result.Error = err.Error()
+		}
+		if withRegions {
+			for _, r := range i.GetRegions() {
+				result.Regions = append(result.Regions, jsonRegion{
+					X:      r.X,
+					Y:      r.Y,
+					Width:  r.Width,
+					Height: r.Height,
+					Tags:   toJSONTags(r.Tags),
+				})
+			}
+		}
+		if withFrames {
+			for _, f := range i.GetFrames() {
+				frame := jsonFrame{Index: f.Index, Tags: toJSONTags(f.Image.GetTags())}
+				if err := f.Image.GetError(); err != nil {
+					frame.Error = err.Error()
+				}
+				result.Frames = append(result.Frames, frame)
+			}
+		}
+		results = append(results, result)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(results)
+}
+
+func toJSONTags(tags []tag.Tag) []jsonTag {
+	result := make([]jsonTag, 0, len(tags))
+	for _, t := range tags {
+		result = append(result, jsonTag{Label: t.GetLabel(), Synset: t.GetSynsetId(), Confidence: t.GetConfidence()})
+	}
+	return result
 }
 
 func PrintTag(tag tag.Tag) {
+	if tag.GetSynsetId() != "" {
+		fmt.Printf("%s (%s): %f\n", tag.GetLabel(), tag.GetSynsetId(), tag.GetConfidence())
+		return
+	}
 	fmt.Printf("%s: %f\n", tag.GetLabel(), tag.GetConfidence())
 }
 func LoadWord2VecModel(path string) (word2vec.Word2Vec, error) {
@@ -81,7 +194,21 @@ func LoadWord2VecModel(path string) (word2vec.Word2Vec, error) {
 	return w2v, nil
 }
 
-func LoadWordNet(path string) (wn *wordnet.WordNet, err error) {
-	wn, err = wordnet.Parse(path)
+// LoadWordNet loads the wordnet dictionary from the snapshot if the snapshot exists and was compiled
+// from the given dictionary. Otherwise the raw dictionary is parsed. The hypernym closures of the
+// snapshot are registered with the tagger.
+func LoadWordNet(logger *log.Logger, dictPath string, snapshotPath string) (wn *wordnet.WordNet, err error) {
+	snapshot, err := wordnetSnapshot.Load(snapshotPath, dictPath)
+	if err == nil {
+		logger.WithField("file", snapshotPath).Debugln("loaded wordnet snapshot")
+		tagger.SetHypernymClosures(snapshot.WordNet, snapshot.Hypernyms)
+		return snapshot.WordNet, nil
+	}
+
+	if !os.IsNotExist(err) {
+		logger.WithField("file", snapshotPath).WithError(err).Warnln("ignoring wordnet snapshot, run compileWordnet to update it")
+	}
+
+	wn, err = wordnet.Parse(dictPath)
 	return wn, err
 }
@@ -0,0 +1,539 @@
+package config
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/twatzl/imtag/tagger/imageClassifier"
+	"github.com/twatzl/imtag/tagger/imageClassifier/ensembleClassifier"
+	"github.com/twatzl/imtag/tagger/imageClassifier/gocvTfClassifier"
+	"github.com/twatzl/imtag/tagger/imageClassifier/preprocessing"
+	"github.com/twatzl/imtag/tagger/imageClassifier/remoteClassifier"
+	"github.com/twatzl/imtag/tagger/imageClassifier/subprocessClassifier"
+	"github.com/twatzl/imtag/tagger/imageClassifier/tensorflowImageClassifier"
+	"github.com/twatzl/imtag/tagger/imageClassifier/tfServingClassifier"
+	"gocv.io/x/gocv"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Backends which can be used for running a classifier.
+const (
+	BackendTensorFlow           = "tensorflow"
+	BackendTensorFlowSavedModel = "tensorflowSavedModel"
+	BackendGoCV                 = "gocv"
+	BackendRemote               = "remote"
+	BackendTFServing            = "tfServing"
+	BackendSubprocess           = "subprocess"
+	BackendEnsemble             = "ensemble"
+)
+
+// ClassifierDefinition describes a classifier model. The definitions are read from the classifiers
+// key of the config file, e.g.
+//
+//	classifiers:
+//	  - name: inception_v3
+//	    backend: tensorflow
+//	    modelPath: frozen_inception_v3.pb
+//	    inputTag: input
+//	    outputTag: InceptionV3/Predictions/Reshape_1
+//	    labelFile: imagenet_comp_graph_label_strings.txt
+//	    numLabels: 1001
+//	    preprocessing: inception
+//	    inputHeight: 299
+//	    inputWidth: 299
+//	    outputType: probabilities
+//
+// Relative model paths and label files are resolved against the classifier path. For saved models
+// the model path is the directory of the model and the input and output tags can be keys of the
+// signature or be left empty if the signature has only one input and output. Remote classifiers
+// only need a url, see the remoteClassifier package for the protocol. Models served by TensorFlow
+// Serving need the url of the server, the labels and the preprocessing. Subprocess classifiers only
+// need the command to run, see the subprocessClassifier package for the protocol. Ensembles combine
+// other classifiers which are referenced by name.
+type ClassifierDefinition struct {
+	Name    string `mapstructure:"name"`
+	Backend string `mapstructure:"backend"`
+	/* the frozen graph or the saved model directory of the model */
+	ModelPath string `mapstructure:"modelPath"`
+	/* the tag set and signature of a saved model */
+	Tags      []string `mapstructure:"tags"`
+	Signature string   `mapstructure:"signature"`
+	/* the name with which the input node of a graph is labelled */
+	InputTag string `mapstructure:"inputTag"`
+	/* the name with which the output node of a graph is labelled */
+	OutputTag string `mapstructure:"outputTag"`
+	/* the name of the feature layer, e.g. the layer before the output (optional) */
+	FeatureTag string `mapstructure:"featureTag"`
+	/* the name of the file which contains the imagenet label mappings */
+	LabelFile string `mapstructure:"labelFile"`
+	/* the file which contains the WordNet ids of the labels in the same order (optional) */
+	WnidLabelFile string `mapstructure:"wnidLabelFile"`
+	/* the line of the label file which belongs to the first output of the model (see the classifiers) */
+	LabelOffset int `mapstructure:"labelOffset"`
+	/* the number of labels (or classes) the model outputs */
+	NumLabels int `mapstructure:"numLabels"`
+	/* the name of the preprocessing which was used during training of the model (e.g. vgg or inception) */
+	Preprocessing string `mapstructure:"preprocessing"`
+	/* the size of the images the model expects as input */
+	InputHeight int `mapstructure:"inputHeight"`
+	InputWidth  int `mapstructure:"inputWidth"`
+	/* whether the model outputs probabilities or logits */
+	OutputType string `mapstructure:"outputType"`
+	/* the temperature of the softmax, can be fitted with the calibrate command (0 means 1) */
+	Temperature float32 `mapstructure:"temperature"`
+	/* the test-time augmentation (none, fiveCrop or tenCrop), can be overridden when tagging */
+	Augmentation string `mapstructure:"augmentation"`
+
+	/* settings of the remote and tensorflow serving backends. timeout and retries are used by the
+	   subprocess backend as well, if retries is not set the default of the backend is used */
+	URL       string        `mapstructure:"url"`
+	Timeout   time.Duration `mapstructure:"timeout"`
+	Retries   *int          `mapstructure:"retries"`
+	InputType string        `mapstructure:"inputType"`
+	/* the name and version of a model served by tensorflow serving. the name defaults to the name of
+	   the definition and the latest version is used if no version is given */
+	ModelName    string `mapstructure:"modelName"`
+	ModelVersion int64  `mapstructure:"modelVersion"`
+
+	/* the executable and its arguments for the subprocess backend */
+	Command string   `mapstructure:"command"`
+	Args    []string `mapstructure:"args"`
+
+	/* the classifiers combined by the ensemble backend, their weights and the merge method (mean or max) */
+	Members []string  `mapstructure:"members"`
+	Weights []float32 `mapstructure:"weights"`
+	Merge   string    `mapstructure:"merge"`
+}
+
+// defaultClassifierDefinitions are the classifiers which are available without any configuration.
+var defaultClassifierDefinitions = []ClassifierDefinition{
+	{
+		Name:      "VGG19",
+		Backend:   BackendTensorFlow,
+		ModelPath: "frozen_vgg_19.pb",
+		InputTag:  "input",
+		OutputTag: "vgg_19/fc8/squeezed",
+		LabelFile: "imagenet_comp_graph_label_strings.txt",
+		// the label file starts with a "dummy" line for the background class, which VGG does not have
+		LabelOffset:   1,
+		NumLabels:     1000,
+		Preprocessing: preprocessing.VGG,
+		InputHeight:   224,
+		InputWidth:    224,
+		OutputType:    imageClassifier.OutputLogits,
+	},
+	{
+		Name:      "resnet_v2_152",
+		Backend:   BackendTensorFlow,
+		ModelPath: "frozen_resnet_v2_152.pb",
+		InputTag:  "input",
+		OutputTag: "resnet_v2_152/predictions/Reshape_1",
+		LabelFile: "imagenet_comp_graph_label_strings.txt",
+		NumLabels: 1001,
+		// ResNet V2 models are trained with inception preprocessing and evaluated at 299x299
+		Preprocessing: preprocessing.Inception,
+		InputHeight:   299,
+		InputWidth:    299,
+		OutputType:    imageClassifier.OutputProbabilities,
+	},
+	{
+		Name:          "gocv_resnet",
+		Backend:       BackendGoCV,
+		ModelPath:     "frozen_resnet_v2_152.pb",
+		InputTag:      "input",
+		OutputTag:     "resnet_v2_152/predictions/Reshape_1",
+		LabelFile:     "imagenet_comp_graph_label_strings.txt",
+		NumLabels:     1001,
+		Preprocessing: preprocessing.Inception,
+		InputHeight:   299,
+		InputWidth:    299,
+		OutputType:    imageClassifier.OutputProbabilities,
+	},
+	{
+		Name:      "gocv_vgg",
+		Backend:   BackendGoCV,
+		ModelPath: "frozen_vgg_19.pb",
+		InputTag:  "input",
+		OutputTag: "vgg_19/fc8/squeezed",
+		LabelFile: "imagenet_comp_graph_label_strings.txt",
+		// the label file starts with a "dummy" line for the background class, which VGG does not have
+		LabelOffset:   1,
+		NumLabels:     1000,
+		Preprocessing: preprocessing.VGG,
+		InputHeight:   224,
+		InputWidth:    224,
+		OutputType:    imageClassifier.OutputLogits,
+	},
+}
+
+// classifierBackends creates the description of a classifier for each backend. New backends have
+// to be registered here, new models only need a definition in the config file.
+var classifierBackends = map[string]func(def ClassifierDefinition) ImageClassifierDesc{
+	BackendTensorFlow: func(def ClassifierDefinition) ImageClassifierDesc {
+		return NewTensorFlowClassifierDesc(
+			def.Name,
+			def.ModelPath,
+			getCompletePathToClassifier,
+			tensorFlowFrozenModelInitializer,
+			tensorflowImageClassifier.Config{
+				InputTag:      def.InputTag,
+				OutputTag:     def.OutputTag,
+				FeatureTag:    def.FeatureTag,
+				LabelFile:     def.LabelFile,
+				WnidLabelFile: def.WnidLabelFile,
+				LabelOffset:   def.LabelOffset,
+				NumLabels:     def.NumLabels,
+				Preprocessing: def.Preprocessing,
+				InputHeight:   def.InputHeight,
+				InputWidth:    def.InputWidth,
+				OutputType:    def.OutputType,
+				Temperature:   def.Temperature,
+				Augmentation:  def.Augmentation,
+			},
+		)
+	},
+	BackendTensorFlowSavedModel: func(def ClassifierDefinition) ImageClassifierDesc {
+		return NewTensorFlowSavedModelDesc(
+			def.Name,
+			def.ModelPath,
+			getCompletePathToClassifier,
+			tensorFlowSavedModelInitializer,
+			tensorflowImageClassifier.Config{
+				GraphTags:     def.Tags,
+				Signature:     def.Signature,
+				InputTag:      def.InputTag,
+				OutputTag:     def.OutputTag,
+				FeatureTag:    def.FeatureTag,
+				LabelFile:     def.LabelFile,
+				WnidLabelFile: def.WnidLabelFile,
+				LabelOffset:   def.LabelOffset,
+				NumLabels:     def.NumLabels,
+				Preprocessing: def.Preprocessing,
+				InputHeight:   def.InputHeight,
+				InputWidth:    def.InputWidth,
+				OutputType:    def.OutputType,
+				Temperature:   def.Temperature,
+				Augmentation:  def.Augmentation,
+			},
+		)
+	},
+	BackendRemote: func(def ClassifierDefinition) ImageClassifierDesc {
+		return NewRemoteClassifierDesc(
+			def.Name,
+			def.URL,
+			remoteClassifierInitializer,
+			remoteClassifier.Config{
+				URL:           def.URL,
+				Timeout:       def.Timeout,
+				Retries:       def.Retries,
+				InputType:     def.InputType,
+				Preprocessing: def.Preprocessing,
+				InputHeight:   def.InputHeight,
+				InputWidth:    def.InputWidth,
+			},
+		)
+	},
+	BackendTFServing: func(def ClassifierDefinition) ImageClassifierDesc {
+		modelName := def.ModelName
+		if modelName == "" {
+			modelName = def.Name
+		}
+
+		classifierConfig := tfServingClassifier.Config{
+			URL:           def.URL,
+			ModelName:     modelName,
+			ModelVersion:  def.ModelVersion,
+			Signature:     def.Signature,
+			InputTag:      def.InputTag,
+			OutputTag:     def.OutputTag,
+			LabelFile:     def.LabelFile,
+			WnidLabelFile: def.WnidLabelFile,
+			LabelOffset:   def.LabelOffset,
+			NumLabels:     def.NumLabels,
+			Preprocessing: def.Preprocessing,
+			InputHeight:   def.InputHeight,
+			InputWidth:    def.InputWidth,
+			OutputType:    def.OutputType,
+			Temperature:   def.Temperature,
+			Augmentation:  def.Augmentation,
+			Timeout:       def.Timeout,
+			Retries:       def.Retries,
+		}
+		return NewTFServingClassifierDesc(
+			def.Name,
+			classifierConfig.PredictURL(),
+			getCompletePathToClassifier,
+			tfServingClassifierInitializer,
+			classifierConfig,
+		)
+	},
+	BackendSubprocess: func(def ClassifierDefinition) ImageClassifierDesc {
+		return NewSubprocessClassifierDesc(
+			def.Name,
+			def.Command,
+			subprocessClassifierInitializer,
+			subprocessClassifier.Config{
+				Command: def.Command,
+				Args:    def.Args,
+				Timeout: def.Timeout,
+				Retries: def.Retries,
+			},
+		)
+	},
+	BackendGoCV: func(def ClassifierDefinition) ImageClassifierDesc {
+		return NewGoCVClassifierDesc(
+			def.Name,
+			def.ModelPath,
+			getCompletePathToClassifier,
+			gocvTensorFlowModelInitializer,
+			gocvTfClassifier.Config{
+				InputTag:      def.InputTag,
+				OutputTag:     def.OutputTag,
+				FeatureTag:    def.FeatureTag,
+				LabelFile:     def.LabelFile,
+				WnidLabelFile: def.WnidLabelFile,
+				LabelOffset:   def.LabelOffset,
+				NumLabels:     def.NumLabels,
+				Preprocessing: def.Preprocessing,
+				InputHeight:   def.InputHeight,
+				InputWidth:    def.InputWidth,
+				OutputType:    def.OutputType,
+				Temperature:   def.Temperature,
+				Augmentation:  def.Augmentation,
+				Backend:       gocv.NetBackendOpenCV,
+				Target:        gocv.NetTargetCPU,
+			},
+		)
+	},
+}
+
+// the ensemble backend is registered in init because its initializer looks up the other
+// classifiers, which would otherwise be an initialization cycle.
+func init() {
+	classifierBackends[BackendEnsemble] = func(def ClassifierDefinition) ImageClassifierDesc {
+		return NewEnsembleClassifierDesc(
+			def.Name,
+			def.Members,
+			def.Weights,
+			ensembleClassifierInitializer,
+			ensembleClassifier.Config{
+				Merge: def.Merge,
+			},
+		)
+	}
+}
+
+// Verify checks if the definition contains everything needed to instantiate the classifier.
+func (def ClassifierDefinition) Verify() (bool, []error) {
+	errorsFound := []error{}
+
+	if def.Name == "" {
+		errorsFound = append(errorsFound, errors.New("name must not be empty"))
+	}
+	if _, ok := classifierBackends[def.Backend]; !ok {
+		errorsFound = append(errorsFound, errors.New(fmt.Sprintf("unknown backend %s. allowed values: %s", def.Backend, GetClassifierBackendNames())))
+	}
+
+	switch def.Backend {
+	case BackendRemote:
+		errorsFound = append(errorsFound, def.verifyRemote()...)
+	case BackendTFServing:
+		errorsFound = append(errorsFound, def.verifyTFServing()...)
+	case BackendEnsemble:
+		errorsFound = append(errorsFound, def.verifyEnsemble()...)
+	case BackendSubprocess:
+		if def.Command == "" {
+			errorsFound = append(errorsFound, errors.New("command must not be empty"))
+		}
+	default:
+		errorsFound = append(errorsFound, def.verifyModel()...)
+	}
+
+	if _, err := preprocessing.New(def.Preprocessing, def.InputHeight, def.InputWidth); err != nil {
+		errorsFound = append(errorsFound, err)
+	}
+	if err := preprocessing.VerifyAugmentation(def.Augmentation); err != nil {
+		errorsFound = append(errorsFound, err)
+	}
+
+	return len(errorsFound) == 0, errorsFound
+}
+
+// verifyModel checks the fields needed by backends which run the model themselves.
+func (def ClassifierDefinition) verifyModel() []error {
+	errorsFound := []error{}
+
+	if def.ModelPath == "" {
+		errorsFound = append(errorsFound, errors.New("modelPath must not be empty"))
+	}
+	// saved models can take the tensors from their signature
+	if def.Backend != BackendTensorFlowSavedModel && (def.InputTag == "" || def.OutputTag == "") {
+		errorsFound = append(errorsFound, errors.New("inputTag and outputTag must not be empty"))
+	}
+	if def.LabelFile == "" {
+		errorsFound = append(errorsFound, errors.New("labelFile must not be empty"))
+	}
+	errorsFound = append(errorsFound, def.verifyLabelsAndOutput()...)
+
+	return errorsFound
+}
+
+// verifyTFServing checks the fields needed by the tensorflow serving backend.
+func (def ClassifierDefinition) verifyTFServing() []error {
+	errorsFound := []error{}
+
+	if def.URL == "" {
+		errorsFound = append(errorsFound, errors.New("url must not be empty"))
+	}
+	if def.LabelFile == "" {
+		errorsFound = append(errorsFound, errors.New("labelFile must not be empty"))
+	}
+	errorsFound = append(errorsFound, def.verifyLabelsAndOutput()...)
+
+	return errorsFound
+}
+
+// verifyEnsemble checks the fields needed by the ensemble backend. Whether the members exist is
+// checked when the ensemble is instantiated.
+func (def ClassifierDefinition) verifyEnsemble() []error {
+	errorsFound := []error{}
+
+	if len(def.Members) == 0 {
+		errorsFound = append(errorsFound, errors.New("members must not be empty"))
+	}
+	for _, member := range def.Members {
+		if member == def.Name {
+			errorsFound = append(errorsFound, errors.New("ensemble must not contain itself"))
+		}
+	}
+	if len(def.Weights) != 0 && len(def.Weights) != len(def.Members) {
+		errorsFound = append(errorsFound, errors.New("there must be one weight for each member"))
+	}
+	if def.Merge != "" && def.Merge != ensembleClassifier.MergeMean && def.Merge != ensembleClassifier.MergeMax {
+		errorsFound = append(errorsFound, errors.New(fmt.Sprintf("merge must be %s or %s",
+			ensembleClassifier.MergeMean, ensembleClassifier.MergeMax)))
+	}
+
+	return errorsFound
+}
+
+func (def ClassifierDefinition) verifyLabelsAndOutput() []error {
+	errorsFound := []error{}
+
+	if def.NumLabels <= 0 {
+		errorsFound = append(errorsFound, errors.New("numLabels must be greater than 0"))
+	}
+	if def.OutputType != "" && def.OutputType != imageClassifier.OutputProbabilities && def.OutputType != imageClassifier.OutputLogits {
+		errorsFound = append(errorsFound, errors.New(fmt.Sprintf("outputType must be %s or %s",
+			imageClassifier.OutputProbabilities, imageClassifier.OutputLogits)))
+	}
+	if def.Temperature < 0 {
+		errorsFound = append(errorsFound, errors.New("temperature must not be negative"))
+	}
+
+	return errorsFound
+}
+
+// verifyRemote checks the fields needed by the remote backend. The server returns labels and
+// probabilities, so no model or labels are needed.
+func (def ClassifierDefinition) verifyRemote() []error {
+	errorsFound := []error{}
+
+	if def.URL == "" {
+		errorsFound = append(errorsFound, errors.New("url must not be empty"))
+	}
+	if def.InputType != "" && def.InputType != remoteClassifier.InputImage && def.InputType != remoteClassifier.InputTensor {
+		errorsFound = append(errorsFound, errors.New(fmt.Sprintf("inputType must be %s or %s",
+			remoteClassifier.InputImage, remoteClassifier.InputTensor)))
+	}
+
+	return errorsFound
+}
+
+// GetClassifierDefinitions returns the built-in classifier definitions together with the ones from
+// the config file. A definition in the config file replaces a built-in one with the same name.
+func GetClassifierDefinitions() ([]ClassifierDefinition, error) {
+	var configured []ClassifierDefinition
+	if err := viper.UnmarshalKey(KeyClassifiers, &configured); err != nil {
+		return nil, errors.Wrap(err, "could not read classifier definitions from config")
+	}
+
+	byName := map[string]ClassifierDefinition{}
+	for _, def := range defaultClassifierDefinitions {
+		byName[def.Name] = def
+	}
+	for _, def := range configured {
+		byName[def.Name] = def
+	}
+
+	definitions := make([]ClassifierDefinition, 0, len(byName))
+	for _, def := range byName {
+		definitions = append(definitions, def)
+	}
+	sort.Slice(definitions, func(i, j int) bool {
+		return definitions[i].Name < definitions[j].Name
+	})
+
+	// an ensemble which contains itself through other ensembles could never be instantiated
+	if cycle := findEnsembleCycle(definitions); cycle != nil {
+		return nil, errors.New(fmt.Sprintf("ensembles must not contain each other: %s", strings.Join(cycle, " -> ")))
+	}
+
+	return definitions, nil
+}
+
+// findEnsembleCycle returns the names of the ensembles which contain each other, starting and ending
+// with the same ensemble, or nil if there is no cycle.
+func findEnsembleCycle(definitions []ClassifierDefinition) []string {
+	ensembles := map[string]ClassifierDefinition{}
+	for _, def := range definitions {
+		if def.Backend == BackendEnsemble {
+			ensembles[def.Name] = def
+		}
+	}
+
+	// ensembles which are done have no cycle, the path holds the ensembles which are searched
+	done := map[string]bool{}
+	var path []string
+	var search func(name string) []string
+	search = func(name string) []string {
+		for i, n := range path {
+			if n == name {
+				return append(append([]string{}, path[i:]...), name)
+			}
+		}
+		def, ok := ensembles[name]
+		if !ok || done[name] {
+			return nil
+		}
+
+		path = append(path, name)
+		for _, member := range def.Members {
+			if cycle := search(member); cycle != nil {
+				return cycle
+			}
+		}
+		path = path[:len(path)-1]
+		done[name] = true
+		return nil
+	}
+
+	for _, def := range definitions {
+		if cycle := search(def.Name); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
+
+// GetClassifierBackendNames returns the names of all backends which can be used in a classifier definition.
+func GetClassifierBackendNames() []string {
+	var names []string
+	for k := range classifierBackends {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
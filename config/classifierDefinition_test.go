@@ -0,0 +1,42 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestGetClassifierDefinitions_EnsembleCycle(t *testing.T) {
+	viper.Set(KeyClassifiers, []interface{}{
+		map[string]interface{}{"name": "a", "backend": BackendEnsemble, "members": []string{"VGG19", "b"}},
+		map[string]interface{}{"name": "b", "backend": BackendEnsemble, "members": []string{"a"}},
+	})
+	defer viper.Set(KeyClassifiers, nil)
+
+	_, err := GetClassifierDefinitions()
+	if err == nil || !strings.Contains(err.Error(), "a -> b -> a") {
+		t.Fatalf("expected an error for ensembles which contain each other but got %v", err)
+	}
+}
+
+func TestFindEnsembleCycle(t *testing.T) {
+	definitions := []ClassifierDefinition{
+		{Name: "a", Backend: BackendEnsemble, Members: []string{"b", "c"}},
+		{Name: "b", Backend: BackendEnsemble, Members: []string{"VGG19"}},
+		{Name: "c", Backend: BackendEnsemble, Members: []string{"b", "d"}},
+		{Name: "d", Backend: BackendEnsemble, Members: []string{"c"}},
+		{Name: "VGG19", Backend: BackendTensorFlow},
+	}
+
+	want := []string{"c", "d", "c"}
+	if got := findEnsembleCycle(definitions); !reflect.DeepEqual(got, want) {
+		t.Errorf("findEnsembleCycle() = %v, want %v", got, want)
+	}
+
+	// members which are shared by several ensembles are no cycle
+	if got := findEnsembleCycle(definitions[:3]); got != nil {
+		t.Errorf("findEnsembleCycle() = %v, want nil", got)
+	}
+}
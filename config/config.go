@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"github.com/pkg/errors"
 	"github.com/spf13/viper"
+	"github.com/twatzl/imtag/tagger"
+	"github.com/twatzl/imtag/tagger/imageClassifier/preprocessing"
 	"os"
 	"path"
 )
@@ -15,6 +17,7 @@ const FlagWord2VecModel = "w2v"
 const FlagWordNetDictionary = "wordnet"
 const FlagLabel = "label"
 const FlagLabelKeyForSearch = "search_label" // used to avoid key conflict with FlagLabel
+const FlagLabelKeyForNeighbors = "neighbors_label" // used to avoid key conflict with FlagLabel
 const FlagLabelFile = "labelFile"
 const FlagFile = "file"
 const FlagK = "numResults"
@@ -22,6 +25,27 @@ const FlagConfidence = "confidence"
 const FlagDataPath = "data"
 const FlagRawClassifierResults = "rawClassification"
 const FlagHierarchicalEmbedding = "hierarchicalEmbedding"
+const FlagLabelStore = "labelStore"
+const FlagOutputLabelStore = "outputLabelStore"
+const FlagSourceWordNetDictionary = "sourceWordnet"
+const FlagWordNetMappingFile = "wordnetMapping"
+const FlagWordNetSnapshot = "wordnetSnapshot"
+const FlagNumNeighbors = "numNeighbors"
+const FlagBatchSize = "batchSize"
+const FlagAugmentation = "augmentation"
+const FlagTiles = "tiles"
+const FlagTileOverlap = "tileOverlap"
+const FlagJSONOutput = "json"
+const FlagRegionBoxes = "regionBoxes"
+const FlagFrames = "frames"
+const FlagSequenceMerge = "sequenceMerge"
+const FlagFrameDetail = "frameDetail"
+const FlagValidationPath = "validation"
+const FlagModel = "model"
+const FlagGraphTags = "tags"
+
+/* Keys which can only be set in the config file */
+const KeyClassifiers = "classifiers"
 
 func InitConfigWithDefaultValues() {
 	viper.SetDefault(FlagClassifierName, "VGG19")
@@ -33,6 +57,13 @@ func InitConfigWithDefaultValues() {
 	viper.SetDefault(FlagK, 0)
 	viper.SetDefault(FlagConfidence, 0)
 	viper.SetDefault(FlagDataPath, "")
+	viper.SetDefault(FlagLabelStore, "./labelstore")
+	viper.SetDefault(FlagNumNeighbors, 10)
+	viper.SetDefault(FlagBatchSize, 16)
+	viper.SetDefault(FlagTiles, 0)
+	viper.SetDefault(FlagTileOverlap, 0.25)
+	viper.SetDefault(FlagFrames, 8)
+	viper.SetDefault(FlagSequenceMerge, tagger.SequenceMergeMean)
 }
 
 // VerifyConfigForEmbedLabel checks if all parameters needed for embedding a new label are set.
@@ -63,6 +94,55 @@ func VerifyConfigForEmbedLabel() (bool, []error) {
 	return isValid, errorsFound
 }
 
+// VerifyConfigForSearchLabel checks if all parameters needed for searching a label are set.
+func VerifyConfigForSearchLabel() (bool, []error) {
+	errorsFound := []error{}
+	isValid := true
+
+	if viper.GetString(FlagLabelKeyForSearch) == "" {
+		isValid = false
+		errorsFound = append(errorsFound, errors.New("label must not be empty"))
+	}
+
+	ok, err := IsWord2VecPathValid()
+	if !ok {
+		isValid = false
+		errorsFound = append(errorsFound, err)
+	}
+
+	ok, err = IsWordNetPathValid()
+	if !ok {
+		isValid = false
+		errorsFound = append(errorsFound, err)
+	}
+
+	return isValid, errorsFound
+}
+
+// VerifyConfigForNeighbors checks if all parameters needed for searching the nearest words are set.
+func VerifyConfigForNeighbors() (bool, []error) {
+	errorsFound := []error{}
+	isValid := true
+
+	if viper.GetString(FlagLabelKeyForNeighbors) == "" {
+		isValid = false
+		errorsFound = append(errorsFound, errors.New("label must not be empty"))
+	}
+
+	if GetNumNeighbors() <= 0 {
+		isValid = false
+		errorsFound = append(errorsFound, errors.New(fmt.Sprintf("%s must be greater than 0", FlagNumNeighbors)))
+	}
+
+	ok, err := IsWord2VecPathValid()
+	if !ok {
+		isValid = false
+		errorsFound = append(errorsFound, err)
+	}
+
+	return isValid, errorsFound
+}
+
 func VerifyConfigForTagImages() (bool, []error) {
 	// we want to display all errors to the user so he can fix all at once
 	// TODO currently we do not try to load wordnet or w2v. maybe we should try that
@@ -100,6 +180,116 @@ func VerifyConfigForTagImages() (bool, []error) {
 		}
 	}
 
+	if err := preprocessing.VerifyAugmentation(GetAugmentation()); err != nil {
+		isValid = false
+		errorsFound = append(errorsFound, err)
+	}
+
+	if GetTiles() < 0 {
+		isValid = false
+		errorsFound = append(errorsFound, errors.New(fmt.Sprintf("%s must not be negative", FlagTiles)))
+	}
+	if overlap := GetTileOverlap(); overlap < 0 || overlap >= 1 {
+		isValid = false
+		errorsFound = append(errorsFound, errors.New(fmt.Sprintf("%s must be at least 0 and less than 1", FlagTileOverlap)))
+	}
+
+	if GetMaxFrames() <= 0 {
+		isValid = false
+		errorsFound = append(errorsFound, errors.New(fmt.Sprintf("%s must be greater than 0", FlagFrames)))
+	}
+	if merge := GetSequenceMerge(); merge != tagger.SequenceMergeMean && merge != tagger.SequenceMergeMax {
+		isValid = false
+		errorsFound = append(errorsFound, errors.New(fmt.Sprintf("%s must be %s or %s",
+			FlagSequenceMerge, tagger.SequenceMergeMean, tagger.SequenceMergeMax)))
+	}
+
+	return isValid, errorsFound
+}
+
+// VerifyConfigForMigrateLabels checks if either a mapping file or a source and target wordnet dictionary
+// are given, so the label store can be migrated.
+func VerifyConfigForMigrateLabels() (bool, []error) {
+	errorsFound := []error{}
+	isValid := true
+
+	mappingFile := GetWordNetMappingFilePath()
+	if mappingFile != "" {
+		info, err := os.Stat(mappingFile)
+		if err != nil {
+			isValid = false
+			errorsFound = append(errorsFound, err)
+		} else if !info.Mode().IsRegular() {
+			isValid = false
+			errorsFound = append(errorsFound, errors.New("wordnet mapping must point to a file"))
+		}
+	} else {
+		if viper.GetString(FlagSourceWordNetDictionary) == "" {
+			isValid = false
+			err := errors.New(fmt.Sprintf("at least one of %s or %s flags must be set", FlagSourceWordNetDictionary, FlagWordNetMappingFile))
+			errorsFound = append(errorsFound, err)
+		} else {
+			info, err := os.Stat(GetSourceWordNetDictionaryPath())
+			if err != nil {
+				isValid = false
+				errorsFound = append(errorsFound, err)
+			} else if !info.Mode().IsDir() {
+				isValid = false
+				errorsFound = append(errorsFound, errors.New("source wordnet path must point to directory"))
+			}
+		}
+
+		ok, err := IsWordNetPathValid()
+		if !ok {
+			isValid = false
+			errorsFound = append(errorsFound, err)
+		}
+	}
+
+	if _, err := os.Stat(GetLabelStorePath()); err != nil {
+		isValid = false
+		errorsFound = append(errorsFound, err)
+	}
+
+	return isValid, errorsFound
+}
+
+// VerifyConfigForCalibrate checks if the validation folder exists and the classifier is valid.
+func VerifyConfigForCalibrate() (bool, []error) {
+	errorsFound := []error{}
+	isValid := true
+
+	info, err := os.Stat(GetValidationPath())
+	if err != nil {
+		isValid = false
+		errorsFound = append(errorsFound, err)
+	} else if !info.Mode().IsDir() {
+		isValid = false
+		errorsFound = append(errorsFound, errors.New("validation path must point to directory"))
+	}
+
+	ok, err := IsClassifierNameValidAndExists()
+	if !ok {
+		isValid = false
+		errorsFound = append(errorsFound, err)
+	}
+
+	return isValid, errorsFound
+}
+
+// VerifyConfigForInspectModel checks if the model to inspect exists.
+func VerifyConfigForInspectModel() (bool, []error) {
+	errorsFound := []error{}
+	isValid := true
+
+	if viper.GetString(FlagModel) == "" {
+		isValid = false
+		errorsFound = append(errorsFound, errors.New("model must not be empty"))
+	} else if _, err := os.Stat(GetModelPathForInspection()); err != nil {
+		isValid = false
+		errorsFound = append(errorsFound, err)
+	}
+
 	return isValid, errorsFound
 }
 
@@ -111,12 +301,25 @@ func GetPathToImageFiles() string {
 // classifiers and if the classifier file or directory does exists.
 func IsClassifierNameValidAndExists() (bool, error) {
 	classifierName := viper.GetString(FlagClassifierName)
-	val, exists := GetKnownClassifierModels()[classifierName]
+	models, err := GetKnownClassifierModels()
+	if err != nil {
+		return false, err
+	}
+	val, exists := models[classifierName]
 	if !exists {
+		if err := verifyClassifierDefinition(classifierName); err != nil {
+			return false, err
+		}
 		return false, errors.New(fmt.Sprintf("classifier %s does not exists", classifierName))
 	}
 
-	classifierPath := getCompletePathToData(val.Path())
+	// remote classifiers have no local files and commands can be on the PATH
+	switch val.(type) {
+	case *RemoteClassifierDesc, *TFServingClassifierDesc, *SubprocessClassifierDesc, *EnsembleClassifierDesc:
+		return true, nil
+	}
+
+	classifierPath := getCompletePathToClassifier(val.Path())
 	info, err := os.Stat(classifierPath)
 	if err != nil {
 		return false, err
@@ -161,8 +364,15 @@ func IsWordNetPathValid() (bool, error) {
 
 func GetClassifierDescription() (ImageClassifierDesc, error) {
 	classifierName := viper.GetString(FlagClassifierName)
-	val, ok := GetKnownClassifierModels()[classifierName]
+	models, err := GetKnownClassifierModels()
+	if err != nil {
+		return nil, err
+	}
+	val, ok := models[classifierName]
 	if !ok {
+		if err := verifyClassifierDefinition(classifierName); err != nil {
+			return nil, err
+		}
 		return nil, errors.New(fmt.Sprintf("could not find classifier with name %s", classifierName))
 	}
 	return val, nil
@@ -176,6 +386,62 @@ func GetWordNetDictionaryPath() string {
 	return getCompletePathToData(viper.GetString(FlagWordNetDictionary))
 }
 
+// GetWordNetSnapshotPath returns the file of the precompiled wordnet dictionary. If it is not
+// configured the snapshot is stored next to the dictionary directory.
+func GetWordNetSnapshotPath() string {
+	snapshotPath := viper.GetString(FlagWordNetSnapshot)
+	if snapshotPath == "" {
+		return path.Clean(GetWordNetDictionaryPath()) + ".snapshot"
+	}
+	return getCompletePathToData(snapshotPath)
+}
+
+func GetSourceWordNetDictionaryPath() string {
+	return getCompletePathToData(viper.GetString(FlagSourceWordNetDictionary))
+}
+
+func GetWordNetMappingFilePath() string {
+	mappingFile := viper.GetString(FlagWordNetMappingFile)
+	if mappingFile == "" {
+		return ""
+	}
+	return getCompletePathToData(mappingFile)
+}
+
+// GetModelPathForInspection returns the path of the model given to inspectModel. Paths which do not
+// exist relative to the working directory are looked up in the classifier path.
+func GetModelPathForInspection() string {
+	modelPath := viper.GetString(FlagModel)
+	if _, err := os.Stat(modelPath); err == nil {
+		return modelPath
+	}
+	return getCompletePathToClassifier(modelPath)
+}
+
+// GetGraphTags returns the tag set with which a saved model is loaded.
+func GetGraphTags() []string {
+	return viper.GetStringSlice(FlagGraphTags)
+}
+
+// GetValidationPath returns the folder with the labelled images used for calibration.
+func GetValidationPath() string {
+	return viper.GetString(FlagValidationPath)
+}
+
+func GetLabelStorePath() string {
+	return viper.GetString(FlagLabelStore)
+}
+
+// GetOutputLabelStorePath returns the path to which a migrated label store is written. If no
+// output is given the label store is migrated in place.
+func GetOutputLabelStorePath() string {
+	output := viper.GetString(FlagOutputLabelStore)
+	if output == "" {
+		return GetLabelStorePath()
+	}
+	return output
+}
+
 func getCompletePathToData(itemPath string) string {
 	if path.IsAbs(itemPath) {
 		return itemPath
@@ -187,8 +453,12 @@ func getCompletePathToData(itemPath string) string {
 }
 
 func getCompletePathToClassifier(classifierFileName string) string {
+	if path.IsAbs(classifierFileName) {
+		return classifierFileName
+	}
+
 	classifierPath := viper.GetString(FlagClassifierPath)
-	if !path.IsAbs(FlagClassifierPath) {
+	if !path.IsAbs(classifierPath) {
 		classifierPath = getCompletePathToData(classifierPath)
 	}
 
@@ -211,6 +481,52 @@ func GetK() int {
 	return viper.GetInt(FlagK)
 }
 
+func GetBatchSize() int {
+	return viper.GetInt(FlagBatchSize)
+}
+
+// GetTiles returns the number of rows and columns of regions an image is split into for tagging.
+func GetTiles() int {
+	return viper.GetInt(FlagTiles)
+}
+
+func GetTileOverlap() float64 {
+	return viper.GetFloat64(FlagTileOverlap)
+}
+
+func JSONOutputEnabled() bool {
+	return viper.GetBool(FlagJSONOutput)
+}
+
+// RegionBoxesEnabled tells whether the regions of the images are printed with their tags.
+func RegionBoxesEnabled() bool {
+	return viper.GetBool(FlagRegionBoxes)
+}
+
+// GetMaxFrames returns the number of frames which are sampled from a sequence.
+func GetMaxFrames() int {
+	return viper.GetInt(FlagFrames)
+}
+
+func GetSequenceMerge() string {
+	return viper.GetString(FlagSequenceMerge)
+}
+
+// FrameDetailEnabled tells whether the tags of each frame of a sequence are printed.
+func FrameDetailEnabled() bool {
+	return viper.GetBool(FlagFrameDetail)
+}
+
+// GetAugmentation returns the augmentation which overrides the one of the classifier definition.
+// If it is empty the augmentation of the definition is used.
+func GetAugmentation() string {
+	return viper.GetString(FlagAugmentation)
+}
+
+func GetNumNeighbors() int {
+	return viper.GetInt(FlagNumNeighbors)
+}
+
 func GetConfidence() float64 {
 	return viper.GetFloat64(FlagConfidence)
 }
\ No newline at end of file
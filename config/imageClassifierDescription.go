@@ -4,11 +4,20 @@ import (
 	"errors"
 	"github.com/sirupsen/logrus"
 	"github.com/twatzl/imtag/tagger/imageClassifier"
+	"github.com/twatzl/imtag/tagger/imageClassifier/ensembleClassifier"
 	"github.com/twatzl/imtag/tagger/imageClassifier/gocvTfClassifier"
+	"github.com/twatzl/imtag/tagger/imageClassifier/remoteClassifier"
+	"github.com/twatzl/imtag/tagger/imageClassifier/subprocessClassifier"
 	"github.com/twatzl/imtag/tagger/imageClassifier/tensorflowImageClassifier"
+	"github.com/twatzl/imtag/tagger/imageClassifier/tfServingClassifier"
 )
 
 type TensorFlowClassifierFactory func(cd *TensorFlowImageClassifierDesc, logger *logrus.Logger) (imageClassifier.ImageClassifier, error)
+type TensorFlowSavedModelFactory func(cd *TensorFlowSavedModelDesc, logger *logrus.Logger) (imageClassifier.ImageClassifier, error)
+type RemoteClassifierFactory func(cd *RemoteClassifierDesc, logger *logrus.Logger) (imageClassifier.ImageClassifier, error)
+type TFServingClassifierFactory func(cd *TFServingClassifierDesc, logger *logrus.Logger) (imageClassifier.ImageClassifier, error)
+type SubprocessClassifierFactory func(cd *SubprocessClassifierDesc, logger *logrus.Logger) (imageClassifier.ImageClassifier, error)
+type EnsembleClassifierFactory func(cd *EnsembleClassifierDesc, logger *logrus.Logger) (imageClassifier.ImageClassifier, error)
 type GoCVClassifierFactory func(cd *GoCVClassifierDesc, logger *logrus.Logger) (imageClassifier.ImageClassifier, error)
 
 // DataPathMapper will add the data path to a resource name.
@@ -49,6 +58,31 @@ func NewTensorFlowClassifierDesc(
 	}
 }
 
+// TensorFlowSavedModelDesc describes a classifier in the SavedModel format. The path refers to the
+// directory of the saved model, the tag set and signature are part of the classifier config.
+type TensorFlowSavedModelDesc struct {
+	imageClassifierDesc
+	instantiateFunc TensorFlowSavedModelFactory
+	classifierConfig tensorflowImageClassifier.Config
+}
+
+func NewTensorFlowSavedModelDesc(
+	modelName,
+	modelPath string,
+	dataPathMapper DataPathMapper,
+	instantiateFunc TensorFlowSavedModelFactory,
+	classifierConfig tensorflowImageClassifier.Config) *TensorFlowSavedModelDesc {
+	return &TensorFlowSavedModelDesc{
+		imageClassifierDesc: imageClassifierDesc{
+			modelName: modelName,
+			modelPath:       modelPath,
+			dataPathMapper:  dataPathMapper,
+		},
+		instantiateFunc: instantiateFunc,
+		classifierConfig: classifierConfig,
+	}
+}
+
 type GoCVClassifierDesc struct {
 	imageClassifierDesc
 	instantiateFunc GoCVClassifierFactory
@@ -72,6 +106,104 @@ func NewGoCVClassifierDesc(
 	}
 }
 
+// RemoteClassifierDesc describes a classifier which runs on a server. The path of the description
+// is the url of the server.
+type RemoteClassifierDesc struct {
+	imageClassifierDesc
+	instantiateFunc RemoteClassifierFactory
+	classifierConfig remoteClassifier.Config
+}
+
+func NewRemoteClassifierDesc(
+	modelName,
+	url string,
+	instantiateFunc RemoteClassifierFactory,
+	classifierConfig remoteClassifier.Config) *RemoteClassifierDesc {
+	return &RemoteClassifierDesc{
+		imageClassifierDesc: imageClassifierDesc{
+			modelName: modelName,
+			modelPath: url,
+		},
+		instantiateFunc: instantiateFunc,
+		classifierConfig: classifierConfig,
+	}
+}
+
+// TFServingClassifierDesc describes a classifier which is served by TensorFlow Serving. The path
+// of the description is the url of the predict endpoint.
+type TFServingClassifierDesc struct {
+	imageClassifierDesc
+	instantiateFunc TFServingClassifierFactory
+	classifierConfig tfServingClassifier.Config
+}
+
+func NewTFServingClassifierDesc(
+	modelName,
+	url string,
+	dataPathMapper DataPathMapper,
+	instantiateFunc TFServingClassifierFactory,
+	classifierConfig tfServingClassifier.Config) *TFServingClassifierDesc {
+	return &TFServingClassifierDesc{
+		imageClassifierDesc: imageClassifierDesc{
+			modelName: modelName,
+			modelPath:       url,
+			dataPathMapper:  dataPathMapper,
+		},
+		instantiateFunc: instantiateFunc,
+		classifierConfig: classifierConfig,
+	}
+}
+
+// SubprocessClassifierDesc describes a classifier which runs as external process. The path of the
+// description is the command which is executed.
+type SubprocessClassifierDesc struct {
+	imageClassifierDesc
+	instantiateFunc SubprocessClassifierFactory
+	classifierConfig subprocessClassifier.Config
+}
+
+func NewSubprocessClassifierDesc(
+	modelName,
+	command string,
+	instantiateFunc SubprocessClassifierFactory,
+	classifierConfig subprocessClassifier.Config) *SubprocessClassifierDesc {
+	return &SubprocessClassifierDesc{
+		imageClassifierDesc: imageClassifierDesc{
+			modelName: modelName,
+			modelPath: command,
+		},
+		instantiateFunc: instantiateFunc,
+		classifierConfig: classifierConfig,
+	}
+}
+
+// EnsembleClassifierDesc describes a classifier which combines other classifiers. The members are
+// referenced by their names.
+type EnsembleClassifierDesc struct {
+	imageClassifierDesc
+	members          []string
+	weights          []float32
+	instantiateFunc  EnsembleClassifierFactory
+	classifierConfig ensembleClassifier.Config
+}
+
+func NewEnsembleClassifierDesc(
+	modelName string,
+	members []string,
+	weights []float32,
+	instantiateFunc EnsembleClassifierFactory,
+	classifierConfig ensembleClassifier.Config) *EnsembleClassifierDesc {
+	return &EnsembleClassifierDesc{
+		imageClassifierDesc: imageClassifierDesc{
+			modelName: modelName,
+		},
+		members:          members,
+		weights:          weights,
+		instantiateFunc:  instantiateFunc,
+		classifierConfig: classifierConfig,
+	}
+}
+
 func (cd *imageClassifierDesc) Name() string {
 	return cd.modelName
 }
@@ -88,6 +220,46 @@ func (cd *TensorFlowImageClassifierDesc) InstantiateClassifier(logger *logrus.Lo
 	return cd.instantiateFunc(cd, logger)
 }
 
+func (cd *TensorFlowSavedModelDesc) InstantiateClassifier(logger *logrus.Logger) (imageClassifier.ImageClassifier, error) {
+	if cd == nil {
+		return nil, errors.New("classifier description is nil")
+	}
+
+	return cd.instantiateFunc(cd, logger)
+}
+
+func (cd *RemoteClassifierDesc) InstantiateClassifier(logger *logrus.Logger) (imageClassifier.ImageClassifier, error) {
+	if cd == nil {
+		return nil, errors.New("classifier description is nil")
+	}
+
+	return cd.instantiateFunc(cd, logger)
+}
+
+func (cd *TFServingClassifierDesc) InstantiateClassifier(logger *logrus.Logger) (imageClassifier.ImageClassifier, error) {
+	if cd == nil {
+		return nil, errors.New("classifier description is nil")
+	}
+
+	return cd.instantiateFunc(cd, logger)
+}
+
+func (cd *SubprocessClassifierDesc) InstantiateClassifier(logger *logrus.Logger) (imageClassifier.ImageClassifier, error) {
+	if cd == nil {
+		return nil, errors.New("classifier description is nil")
+	}
+
+	return cd.instantiateFunc(cd, logger)
+}
+
+func (cd *EnsembleClassifierDesc) InstantiateClassifier(logger *logrus.Logger) (imageClassifier.ImageClassifier, error) {
+	if cd == nil {
+		return nil, errors.New("classifier description is nil")
+	}
+
+	return cd.instantiateFunc(cd, logger)
+}
+
 func (cd *GoCVClassifierDesc) InstantiateClassifier(logger *logrus.Logger) (imageClassifier.ImageClassifier, error) {
 	if cd == nil {
 		return nil, errors.New("classifier description is nil")
@@ -1,15 +1,27 @@
 package config
 
 import (
+	"fmt"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/twatzl/imtag/tagger/imageClassifier"
+	"github.com/twatzl/imtag/tagger/imageClassifier/ensembleClassifier"
 	"github.com/twatzl/imtag/tagger/imageClassifier/gocvTfClassifier"
+	"github.com/twatzl/imtag/tagger/imageClassifier/remoteClassifier"
+	"github.com/twatzl/imtag/tagger/imageClassifier/subprocessClassifier"
 	"github.com/twatzl/imtag/tagger/imageClassifier/tensorflowImageClassifier"
-	"gocv.io/x/gocv"
+	"github.com/twatzl/imtag/tagger/imageClassifier/tfServingClassifier"
+	"io"
 )
 
 var tensorFlowFrozenModelInitializer = func(cd *TensorFlowImageClassifierDesc, logger *logrus.Logger) (imageClassifier.ImageClassifier, error) {
-	classifier := tensorflowImageClassifier.New(cd.dataPathMapper, logger, cd.classifierConfig)
+	classifierConfig := cd.classifierConfig
+	classifierConfig.BatchSize = GetBatchSize()
+	if augmentation := GetAugmentation(); augmentation != "" {
+		classifierConfig.Augmentation = augmentation
+	}
+
+	classifier := tensorflowImageClassifier.New(cd.dataPathMapper, logger, classifierConfig)
 	err := classifier.LoadFrozenModel(cd.Path())
 	if err != nil {
 		return nil, err
@@ -18,8 +30,96 @@ var tensorFlowFrozenModelInitializer = func(cd *TensorFlowImageClassifierDesc, l
 	return classifier, nil
 }
 
+var tensorFlowSavedModelInitializer = func(cd *TensorFlowSavedModelDesc, logger *logrus.Logger) (imageClassifier.ImageClassifier, error) {
+	classifierConfig := cd.classifierConfig
+	classifierConfig.BatchSize = GetBatchSize()
+	if augmentation := GetAugmentation(); augmentation != "" {
+		classifierConfig.Augmentation = augmentation
+	}
+
+	classifier := tensorflowImageClassifier.New(cd.dataPathMapper, logger, classifierConfig)
+	err := classifier.LoadSavedModel(cd.Path())
+	if err != nil {
+		return nil, err
+	}
+
+	return classifier, nil
+}
+
+var remoteClassifierInitializer = func(cd *RemoteClassifierDesc, logger *logrus.Logger) (imageClassifier.ImageClassifier, error) {
+	classifierConfig := cd.classifierConfig
+	classifierConfig.BatchSize = GetBatchSize()
+
+	return remoteClassifier.New(logger, classifierConfig), nil
+}
+
+var tfServingClassifierInitializer = func(cd *TFServingClassifierDesc, logger *logrus.Logger) (imageClassifier.ImageClassifier, error) {
+	classifierConfig := cd.classifierConfig
+	classifierConfig.BatchSize = GetBatchSize()
+	if augmentation := GetAugmentation(); augmentation != "" {
+		classifierConfig.Augmentation = augmentation
+	}
+
+	return tfServingClassifier.New(cd.dataPathMapper, logger, classifierConfig), nil
+}
+
+var subprocessClassifierInitializer = func(cd *SubprocessClassifierDesc, logger *logrus.Logger) (imageClassifier.ImageClassifier, error) {
+	classifierConfig := cd.classifierConfig
+	classifierConfig.BatchSize = GetBatchSize()
+
+	return subprocessClassifier.New(logger, classifierConfig), nil
+}
+
+var ensembleClassifierInitializer = func(cd *EnsembleClassifierDesc, logger *logrus.Logger) (imageClassifier.ImageClassifier, error) {
+	models, err := GetKnownClassifierModels()
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]ensembleClassifier.Member, 0, len(cd.members))
+	closeMembers := func() {
+		for _, member := range members {
+			if closer, ok := member.Classifier.(io.Closer); ok {
+				closer.Close()
+			}
+		}
+	}
+
+	for i, name := range cd.members {
+		memberDesc, ok := models[name]
+		if !ok {
+			closeMembers()
+			return nil, errors.New(fmt.Sprintf("classifier %s of ensemble %s does not exist", name, cd.Name()))
+		}
+
+		classifier, err := memberDesc.InstantiateClassifier(logger)
+		if err != nil {
+			closeMembers()
+			return nil, errors.Wrapf(err, "could not load classifier %s of ensemble %s", name, cd.Name())
+		}
+
+		member := ensembleClassifier.Member{Name: name, Classifier: classifier}
+		if i < len(cd.weights) {
+			member.Weight = cd.weights[i]
+		}
+		members = append(members, member)
+	}
+
+	classifier, err := ensembleClassifier.New(logger, cd.classifierConfig, members)
+	if err != nil {
+		closeMembers()
+		return nil, err
+	}
+	return classifier, nil
+}
+
 var gocvTensorFlowModelInitializer = func(cd *GoCVClassifierDesc, logger *logrus.Logger) (imageClassifier.ImageClassifier, error) {
-	classifier := gocvTfClassifier.New(cd.dataPathMapper, logger, cd.classifierConfig)
+	classifierConfig := cd.classifierConfig
+	if augmentation := GetAugmentation(); augmentation != "" {
+		classifierConfig.Augmentation = augmentation
+	}
+
+	classifier := gocvTfClassifier.New(cd.dataPathMapper, logger, classifierConfig)
 	err := classifier.LoadFrozenModel(cd.Path())
 	if err != nil {
 		return nil, err
@@ -28,74 +128,53 @@ var gocvTensorFlowModelInitializer = func(cd *GoCVClassifierDesc, logger *logrus
 	return classifier, nil
 }
 
-// knownImageClassifiers allows to refer to different classifiers using only a name instead of a path and type
-// combination in the flags. This makes things easier for the user. The downside is that developers have to
-// enter their classifiers here first.
-var knownImageClassifiers = map[string]ImageClassifierDesc{
-	"VGG19": NewTensorFlowClassifierDesc(
-		"VGG19",
-		"frozen_vgg_19.pb",
-		getCompletePathToClassifier,
-		tensorFlowFrozenModelInitializer,
-		tensorflowImageClassifier.Config{
-			InputTag:  "input",
-			OutputTag: "vgg_19/fc8/squeezed",
-			LabelFile: "imagenet_comp_graph_label_strings.txt",
-			NumLabels: 1000,
-		},
-	),
-	"resnet_v2_152": NewTensorFlowClassifierDesc(
-		"resnet_v2_152",
-		"frozen_resnet_v2_152.pb",
-		getCompletePathToClassifier,
-		tensorFlowFrozenModelInitializer,
-		tensorflowImageClassifier.Config{
-			InputTag:  "input",
-			OutputTag: "resnet_v2_152/predictions/Reshape_1",
-			LabelFile: "imagenet_comp_graph_label_strings.txt",
-			NumLabels: 1001,
-		},
-	),
-	"gocv_resnet": NewGoCVClassifierDesc(
-		"resnet_v2_152",
-		"frozen_resnet_v2_152.pb",
-		getCompletePathToClassifier,
-		gocvTensorFlowModelInitializer,
-		gocvTfClassifier.Config{
-			InputTag:  "input",
-			OutputTag: "resnet_v2_152/predictions/Reshape_1",
-			LabelFile: "imagenet_comp_graph_label_strings.txt",
-			NumLabels: 1001,
-			Backend: gocv.NetBackendOpenCV,
-			Target: gocv.NetTargetCPU,
-		},
-	),
-	"gocv_vgg": NewGoCVClassifierDesc(
-		"VGG19",
-		"frozen_vgg_19.pb",
-		getCompletePathToClassifier,
-		gocvTensorFlowModelInitializer,
-		gocvTfClassifier.Config{
-			InputTag:  "input",
-			OutputTag: "vgg_19/fc8/squeezed",
-			LabelFile: "imagenet_comp_graph_label_strings.txt",
-			NumLabels: 1000,
-			Backend: gocv.NetBackendOpenCV,
-			Target: gocv.NetTargetCPU,
-		},
-	),
+// GetKnownClassifierModels returns the descriptions of all classifiers which are defined, either
+// built-in or in the config file. This allows to refer to different classifiers using only a name
+// instead of a path and type combination in the flags. Invalid definitions are skipped, they are
+// reported by the listClassifiers command.
+func GetKnownClassifierModels() (map[string]ImageClassifierDesc, error) {
+	definitions, err := GetClassifierDefinitions()
+	if err != nil {
+		return nil, err
+	}
+
+	models := map[string]ImageClassifierDesc{}
+	for _, def := range definitions {
+		if ok, _ := def.Verify(); ok {
+			models[def.Name] = classifierBackends[def.Backend](def)
+		}
+	}
+	return models, nil
 }
 
-// GetClassifierModels returns a list of all classifiers that are implemented and
-// registered.
-func GetKnownClassifierModels() map[string]ImageClassifierDesc {
-	return knownImageClassifiers
+// verifyClassifierDefinition returns the first error of the definition with the given name or nil
+// if the definition is valid or does not exist.
+func verifyClassifierDefinition(name string) error {
+	definitions, err := GetClassifierDefinitions()
+	if err != nil {
+		return err
+	}
+
+	for _, def := range definitions {
+		if def.Name != name {
+			continue
+		}
+		if ok, errs := def.Verify(); !ok {
+			return errors.Wrapf(errs[0], "invalid definition of classifier %s", name)
+		}
+	}
+	return nil
 }
 
 func GetKnownClassifierNames() []string {
+	definitions, err := GetClassifierDefinitions()
+	if err != nil {
+		return nil
+	}
+
 	var names []string
-	for k, _ := range knownImageClassifiers {
-		names = append(names, k)
+	for _, def := range definitions {
+		names = append(names, def.Name)
 	}
 	return names
 }
@@ -1,16 +1,41 @@
+"""Example server for the remote classifier backend of imtag.
+
+The request and response format is documented in the remoteClassifier package
+(tagger/imageClassifier/remoteClassifier/remoteClassifier.go). The server classifies the images with a
+Keras ResNet50 trained on ImageNet. Use it with a classifier definition like
+
+  classifiers:
+    - name: keras_resnet50
+      backend: remote
+      url: http://localhost:5000/classify
+      inputType: image
+
+For inputType tensor the definition needs preprocessing: resize with inputHeight and inputWidth 224,
+the server applies the ResNet50 normalization itself.
+"""
+import base64
+import io
+
+import numpy as np
 import tensorflow as tf
-import tensorflow_hub as hub
+from PIL import Image
 from waitress import serve
 from flask import Flask
+from flask import jsonify
 from flask import request
 
 app = Flask(__name__)
 
 host = '0.0.0.0'
 port = 5000
-classifier_name = "classifier_skeleton"
+classifier_name = "keras_resnet50"
 welcome_message = classifier_name + " running on server " + host + ":" + str(port)
 
+input_size = (224, 224)
+num_labels = 1000
+
+model = tf.keras.applications.resnet50.ResNet50(weights='imagenet')
+
 
 @app.route('/')
 def index():
@@ -21,22 +46,52 @@ def index():
 def classify():
     data = request.json
     if data is None:
-        return "Error: mimetype must be application/json !"
+        return jsonify({"error": "mimetype must be application/json"}), 400
 
-    print(data)
-    print(data['hello'])
-    classifier()
-    return "ok"
+    k = data.get('k', 0)
+    if k <= 0 or k > num_labels:
+        k = num_labels
 
+    # images which can not be read get an error, the others are classified in one batch. imtag matches
+    # the results to the images by id.
+    results = []
+    batch = []
+    positions = []
+    for img in data.get('images', []):
+        try:
+            values = load_input(img)
+            results.append({"id": img['id']})
+        except Exception as e:
+            results.append({"id": img.get('id'), "error": str(e)})
+            continue
+        batch.append(values)
+        positions.append(len(results) - 1)
 
-def classifier():
-    tf.enable_eager_execution()
+    if batch:
+        inputs = tf.keras.applications.resnet50.preprocess_input(np.stack(batch))
+        predictions = model.predict(inputs)
+        for i, top in zip(positions, tf.keras.applications.resnet50.decode_predictions(predictions, top=k)):
+            results[i]["labels"] = [name.replace('_', ' ') for _, name, _ in top]
+            results[i]["synsets"] = [wnid for wnid, _, _ in top]
+            results[i]["probabilities"] = [float(p) for _, _, p in top]
 
-    module_url = "https://tfhub.dev/google/tf2-preview/nnlm-en-dim128/1"
-    embed = hub.KerasLayer(module_url)
-    embeddings = embed(["A long sentence.", "single-word",
-                        "http://example.com"])
-    print(embeddings.shape)  #(3,128)
+    return jsonify({"results": results})
+
+
+def load_input(img):
+    """Returns the image as float array of shape [224, 224, 3] with values in [0, 255]."""
+    if 'tensor' in img:
+        tensor = img['tensor']
+        values = np.array(tensor['values'], dtype=np.float32).reshape(tensor['shape'])
+        if values.shape != input_size + (3,):
+            raise ValueError("expected a tensor of shape [224, 224, 3] but got " + str(list(values.shape)))
+        return values
+
+    if 'data' not in img:
+        raise ValueError("image contains neither data nor tensor")
+
+    decoded = Image.open(io.BytesIO(base64.b64decode(img['data']))).convert('RGB')
+    return np.asarray(decoded.resize(input_size, Image.BILINEAR), dtype=np.float32)
 
 
 if __name__ == '__main__':
@@ -44,12 +99,4 @@ if __name__ == '__main__':
     # app.run(debug=True)
     # production
     print(welcome_message)
-    path = "/home/twatzl/go/src/imtag/pics/DSC_9949_small.jpg"
-    image_file = tf.read_file(path)
-    image_decoded = tf.image.decode_jpeg(image_file, channels=3)
-    with tf.Session() as sess:
-        f, img = sess.run([image_file, image_decoded])
-        print(f)
-        print(img)
-
     serve(app, host=host, port=port)
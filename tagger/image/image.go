@@ -7,11 +7,41 @@ type Image interface {
 	GetTags() []tag.Tag
 	SetTags(tags []tag.Tag)
 	AddTag(tag tag.Tag)
+	// GetError returns the error which occurred while processing the image or nil. Images with
+	// an error are skipped by the tagger.
+	GetError() error
+	SetError(err error)
+	// GetRegions returns the regions of the image which were tagged on their own. It is empty
+	// unless the tagger tiles the images.
+	GetRegions() []Region
+	SetRegions(regions []Region)
+	// GetFrames returns the sampled frames of a sequence, e.g. an animated GIF or a burst of photos.
+	// It is empty for images with a single frame.
+	GetFrames() []Frame
+	SetFrames(frames []Frame)
+}
+
+// Region is a part of an image with the tags found in it. The bounds are given in pixels of the
+// image after applying its EXIF orientation.
+type Region struct {
+	X, Y          int
+	Width, Height int
+	Tags          []tag.Tag
+}
+
+// Frame is a frame of a sequence. Index is the position of the frame in the whole sequence, since
+// only some of the frames are sampled.
+type Frame struct {
+	Index int
+	Image Image
 }
 
 type image struct {
 	filename string
 	tags []tag.Tag
+	regions []Region
+	frames []Frame
+	err error
 }
 
 func (i *image) GetFilename() string {
@@ -30,6 +60,30 @@ func (i *image) AddTag(tag tag.Tag) {
 	i.tags = append(i.tags, tag)
 }
 
+func (i *image) GetError() error {
+	return i.err
+}
+
+func (i *image) SetError(err error) {
+	i.err = err
+}
+
+func (i *image) GetRegions() []Region {
+	return i.regions
+}
+
+func (i *image) SetRegions(regions []Region) {
+	i.regions = regions
+}
+
+func (i *image) GetFrames() []Frame {
+	return i.frames
+}
+
+func (i *image) SetFrames(frames []Frame) {
+	i.frames = frames
+}
+
 func New(filename string) Image {
 	return &image{
 		filename: filename,
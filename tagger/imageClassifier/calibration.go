@@ -0,0 +1,74 @@
+package imageClassifier
+
+import (
+	"errors"
+	"math"
+)
+
+// range in which the temperature is searched
+const (
+	minTemperature = 0.05
+	maxTemperature = 20
+	// the search stops when the interval of log temperatures is smaller than this
+	temperatureTolerance = 1e-4
+)
+
+// FitTemperature finds the temperature which minimizes the negative log likelihood of the target
+// labels on a validation set. The probabilities are the output of the classifier for each image
+// (already normalized with the current temperature), targets holds the index of the correct label of
+// each image. The returned temperature has to be multiplied with the current one. Since the negative
+// log likelihood is convex in the inverse temperature a golden-section search is sufficient.
+func FitTemperature(probabilities [][]float32, targets []int) (temperature float32, nllBefore float64, nllAfter float64, err error) {
+	if len(probabilities) != len(targets) {
+		return 0, 0, 0, errors.New("number of predictions and targets must match")
+	}
+	if len(probabilities) == 0 {
+		return 0, 0, 0, errors.New("no samples for calibration")
+	}
+
+	logits := make([][]float32, len(probabilities))
+	for i, p := range probabilities {
+		if targets[i] < 0 || targets[i] >= len(p) {
+			return 0, 0, 0, errors.New("target index out of range")
+		}
+		logits[i] = make([]float32, len(p))
+		for j, v := range p {
+			logits[i][j] = float32(math.Log(math.Max(float64(v), minProbability)))
+		}
+	}
+
+	nll := func(logTemperature float64) float64 {
+		return NegativeLogLikelihood(logits, targets, float32(math.Exp(logTemperature)))
+	}
+
+	// golden-section search on the logarithm of the temperature
+	invPhi := (math.Sqrt(5) - 1) / 2
+	a, b := math.Log(minTemperature), math.Log(maxTemperature)
+	c, d := b-invPhi*(b-a), a+invPhi*(b-a)
+	fc, fd := nll(c), nll(d)
+	for b-a > temperatureTolerance {
+		if fc < fd {
+			b, d, fd = d, c, fc
+			c = b - invPhi*(b-a)
+			fc = nll(c)
+		} else {
+			a, c, fc = c, d, fd
+			d = a + invPhi*(b-a)
+			fd = nll(d)
+		}
+	}
+
+	best := (a + b) / 2
+	return float32(math.Exp(best)), nll(0), nll(best), nil
+}
+
+// NegativeLogLikelihood returns the mean negative log likelihood of the target labels if the logits
+// are normalized with the given temperature.
+func NegativeLogLikelihood(logits [][]float32, targets []int, temperature float32) float64 {
+	sum := 0.0
+	for i, l := range logits {
+		p := Softmax(l, temperature)[targets[i]]
+		sum -= math.Log(math.Max(float64(p), minProbability))
+	}
+	return sum / float64(len(logits))
+}
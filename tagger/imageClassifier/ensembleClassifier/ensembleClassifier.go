@@ -0,0 +1,240 @@
+// The package ensembleClassifier implements an image classifier which combines the predictions of
+// several classifiers. The label spaces of the classifiers are aligned by the synset ids of the labels
+// or by the label strings if there are none, so models with and without a background class can be combined.
+package ensembleClassifier
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"github.com/twatzl/imtag/tagger/image"
+	"github.com/twatzl/imtag/tagger/imageClassifier"
+	"github.com/twatzl/imtag/tagger/tag"
+	"io"
+	"strings"
+)
+
+// Methods for merging the probabilities of the classifiers.
+const (
+	// MergeMean computes the weighted average of the probabilities.
+	MergeMean = "mean"
+	// MergeMax takes the highest probability of any classifier.
+	MergeMax = "max"
+)
+
+// DefaultIgnoredLabels are labels of background or placeholder classes, which are not part of the
+// label space of all models and therefore dropped before merging.
+var DefaultIgnoredLabels = []string{"background", "dummy", ""}
+
+type EnsembleClassifier interface {
+	imageClassifier.ImageClassifier
+	Close() error
+}
+
+type ensembleClassifier struct {
+	log     *logrus.Logger
+	config  Config
+	members []Member
+	ignored map[string]bool
+}
+
+// Member is one of the classifiers of an ensemble.
+type Member struct {
+	Name       string
+	Classifier imageClassifier.ImageClassifier
+	/* the weight of the classifier for the mean (default is 1) */
+	Weight float32
+}
+
+type Config struct {
+	/* how the probabilities are merged (mean or max, default is mean) */
+	Merge string
+	/* labels which are dropped from the predictions (default is DefaultIgnoredLabels) */
+	IgnoredLabels []string
+}
+
+func New(log *logrus.Logger, config Config, members []Member) (EnsembleClassifier, error) {
+	if len(members) == 0 {
+		return nil, errors.New("ensemble needs at least one classifier")
+	}
+	if config.Merge == "" {
+		config.Merge = MergeMean
+	}
+	if config.Merge != MergeMean && config.Merge != MergeMax {
+		return nil, errors.New(fmt.Sprintf("unknown merge method %s", config.Merge))
+	}
+	if config.IgnoredLabels == nil {
+		config.IgnoredLabels = DefaultIgnoredLabels
+	}
+
+	ignored := map[string]bool{}
+	for _, label := range config.IgnoredLabels {
+		ignored[normalizeLabel(label)] = true
+	}
+
+	for i := range members {
+		if members[i].Weight <= 0 {
+			members[i].Weight = 1
+		}
+	}
+
+	return &ensembleClassifier{
+		log:     log,
+		config:  config,
+		members: members,
+		ignored: ignored,
+	}, nil
+}
+
+func (ec *ensembleClassifier) ClassifyImages(images []image.Image) ([][]tag.Tag, error) {
+	// the members always return all predictions, otherwise labels outside of the top k of one
+	// classifier would count as 0 for that classifier
+	memberTags := make([][][]tag.Tag, len(ec.members))
+	for i, member := range ec.members {
+		tags, err := member.Classifier.ClassifyImages(images)
+		if err != nil {
+			ec.log.WithField("classifier", member.Name).WithError(err).Errorln("error during classification")
+			return nil, err
+		}
+		memberTags[i] = tags
+	}
+
+	tags := make([][]tag.Tag, len(images))
+	for imgIdx := range images {
+		predictions := make([][]tag.Tag, len(ec.members))
+		for i := range ec.members {
+			if imgIdx < len(memberTags[i]) {
+				predictions[i] = memberTags[i][imgIdx]
+			}
+		}
+		tags[imgIdx] = ec.merge(predictions)
+	}
+	return tags, nil
+}
+
+func (ec *ensembleClassifier) ClassifyImagesTopK(images []image.Image, k int) ([][]tag.Tag, error) {
+	tags, err := ec.ClassifyImages(images)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range tags {
+		tags[i] = imageClassifier.TopKTags(tags[i], k)
+	}
+	return tags, nil
+}
+
+// Close closes all classifiers of the ensemble.
+func (ec *ensembleClassifier) Close() error {
+	var lastErr error
+	for _, member := range ec.members {
+		if closer, ok := member.Classifier.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				lastErr = err
+			}
+		}
+	}
+	return lastErr
+}
+
+// merge combines the predictions of all members for one image. Members without predictions for the
+// image (e.g. because of an error) are left out.
+func (ec *ensembleClassifier) merge(predictions [][]tag.Tag) []tag.Tag {
+	merged := map[string]float32{}
+	// keep the order in which the labels appear for deterministic results
+	keys := []string{}
+	// the first tag of each label is used for the label and synset id of the merged tag
+	firstTags := map[string]tag.Tag{}
+	var totalWeight float32
+
+	for i, tags := range predictions {
+		if tags == nil {
+			continue
+		}
+		weight := ec.members[i].Weight
+		totalWeight += weight
+
+		for _, t := range ec.normalize(tags) {
+			key := labelKey(t)
+			if _, ok := merged[key]; !ok {
+				keys = append(keys, key)
+				firstTags[key] = t
+			}
+
+			p := t.GetConfidence()
+			switch ec.config.Merge {
+			case MergeMax:
+				if p > merged[key] {
+					merged[key] = p
+				}
+			default:
+				merged[key] += weight * p
+			}
+		}
+	}
+
+	if totalWeight == 0 {
+		return nil
+	}
+
+	tags := make([]tag.Tag, 0, len(keys))
+	for _, key := range keys {
+		p := merged[key]
+		if ec.config.Merge == MergeMean {
+			p /= totalWeight
+		}
+		first := firstTags[key]
+		if first.GetSynsetId() != "" {
+			tags = append(tags, tag.NewWithSynset(first.GetLabel(), first.GetSynsetId(), p))
+		} else {
+			tags = append(tags, tag.New(first.GetLabel(), p))
+		}
+	}
+	return tags
+}
+
+// normalize drops the ignored labels and renormalizes the remaining probabilities so they sum up
+// to 1 again. This way a model with a background class does not get less weight than one without.
+// Tags with the same label are combined.
+func (ec *ensembleClassifier) normalize(tags []tag.Tag) []tag.Tag {
+	probabilities := map[string]float32{}
+	keys := []string{}
+	firstTags := map[string]tag.Tag{}
+	var sum float32
+	for _, t := range tags {
+		if ec.ignored[normalizeLabel(t.GetLabel())] {
+			continue
+		}
+		key := labelKey(t)
+		if _, ok := probabilities[key]; !ok {
+			keys = append(keys, key)
+			firstTags[key] = t
+		}
+		probabilities[key] += t.GetConfidence()
+		sum += t.GetConfidence()
+	}
+
+	normalized := make([]tag.Tag, 0, len(keys))
+	for _, key := range keys {
+		p := probabilities[key]
+		if sum > 0 {
+			p /= sum
+		}
+		first := firstTags[key]
+		normalized = append(normalized, tag.NewWithSynset(first.GetLabel(), first.GetSynsetId(), p))
+	}
+	return normalized
+}
+
+// labelKey returns the key by which the labels of the members are aligned. Labels mapped to a synset
+// are aligned by the synset id, since different models use different strings for the same class.
+func labelKey(t tag.Tag) string {
+	if t.GetSynsetId() != "" {
+		return t.GetSynsetId()
+	}
+	return normalizeLabel(t.GetLabel())
+}
+
+func normalizeLabel(label string) string {
+	return strings.ToLower(strings.TrimSpace(label))
+}
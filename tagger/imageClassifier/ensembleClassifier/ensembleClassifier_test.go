@@ -0,0 +1,104 @@
+package ensembleClassifier
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/twatzl/imtag/tagger/image"
+	"github.com/twatzl/imtag/tagger/tag"
+	"math"
+	"testing"
+)
+
+// fixedClassifier returns the same tags for every image.
+type fixedClassifier struct {
+	tags []tag.Tag
+}
+
+func (fc *fixedClassifier) ClassifyImages(images []image.Image) ([][]tag.Tag, error) {
+	result := make([][]tag.Tag, len(images))
+	for i := range result {
+		result[i] = fc.tags
+	}
+	return result, nil
+}
+
+func (fc *fixedClassifier) ClassifyImagesTopK(images []image.Image, k int) ([][]tag.Tag, error) {
+	return fc.ClassifyImages(images)
+}
+
+func confidences(tags []tag.Tag) map[string]float32 {
+	result := map[string]float32{}
+	for _, t := range tags {
+		result[t.GetLabel()] = t.GetConfidence()
+	}
+	return result
+}
+
+func TestEnsembleClassifier_ClassifyImages(t *testing.T) {
+	// the second model has a background class
+	first := &fixedClassifier{[]tag.Tag{tag.New("cat", 0.8), tag.New("dog", 0.2)}}
+	second := &fixedClassifier{[]tag.Tag{tag.New("background", 0.5), tag.New("cat", 0.1), tag.New("dog", 0.4)}}
+
+	tests := []struct {
+		merge    string
+		expected map[string]float32
+	}{
+		// the second model is renormalized to cat 0.2 and dog 0.8
+		{MergeMean, map[string]float32{"cat": (0.8 + 3*0.2) / 4, "dog": (0.2 + 3*0.8) / 4}},
+		{MergeMax, map[string]float32{"cat": 0.8, "dog": 0.8}},
+	}
+
+	for _, test := range tests {
+		classifier, err := New(logrus.New(), Config{Merge: test.merge}, []Member{
+			{Name: "first", Classifier: first},
+			{Name: "second", Classifier: second, Weight: 3},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tags, err := classifier.ClassifyImages([]image.Image{image.New("a.jpg")})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result := confidences(tags[0])
+		if len(result) != len(test.expected) {
+			t.Errorf("%s: expected %v but got %v", test.merge, test.expected, result)
+		}
+		for label, expected := range test.expected {
+			if math.Abs(float64(result[label]-expected)) > 1e-6 {
+				t.Errorf("%s: expected %v for %s but got %v", test.merge, expected, label, result[label])
+			}
+		}
+	}
+}
+
+func TestEnsembleClassifier_ClassifyImages_AlignsBySynset(t *testing.T) {
+	// the models use different strings for the same synset
+	first := &fixedClassifier{[]tag.Tag{tag.NewWithSynset("tabby", "n02123045", 0.6), tag.NewWithSynset("dog", "n02084071", 0.4)}}
+	second := &fixedClassifier{[]tag.Tag{tag.NewWithSynset("tabby cat", "n02123045", 0.2), tag.NewWithSynset("dog", "n02084071", 0.8)}}
+
+	classifier, err := New(logrus.New(), Config{Merge: MergeMean}, []Member{
+		{Name: "first", Classifier: first},
+		{Name: "second", Classifier: second},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tags, err := classifier.ClassifyImages([]image.Image{image.New("a.jpg")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tags[0]) != 2 {
+		t.Fatalf("expected 2 tags but got %d", len(tags[0]))
+	}
+	tabby := tags[0][0]
+	if tabby.GetLabel() != "tabby" || tabby.GetSynsetId() != "n02123045" {
+		t.Errorf("expected tabby (n02123045) but got %s (%s)", tabby.GetLabel(), tabby.GetSynsetId())
+	}
+	if math.Abs(float64(tabby.GetConfidence())-0.4) > 1e-6 {
+		t.Errorf("expected 0.4 for tabby but got %v", tabby.GetConfidence())
+	}
+}
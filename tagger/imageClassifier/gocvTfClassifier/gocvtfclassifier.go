@@ -2,33 +2,39 @@ package gocvTfClassifier
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"github.com/sirupsen/logrus"
 	"github.com/twatzl/imtag/tagger/image"
 	"github.com/twatzl/imtag/tagger/imageClassifier"
+	"github.com/twatzl/imtag/tagger/imageClassifier/preprocessing"
 	"github.com/twatzl/imtag/tagger/tag"
 	"gocv.io/x/gocv"
 	goimage "image"
 	"os"
 )
 
-
-
 /*
  * gocvTensorFlowClassifier loads a tensorflow model and uses that as classifier. For loading and executing it uses OpenCV
  */
 type GoCVTensorFlowClassifier interface {
 	imageClassifier.ImageClassifier
+	imageClassifier.FeatureExtractor
 	LoadFrozenModel(modelFile string) (err error)
+	Close() error
 }
 
 type gocvTensorFlowClassifier struct {
 	dataPathForResource func(string) string
 	log                 *logrus.Logger
 	labels              []string
+	synsetIds           []string
 	config              Config
 	net                 gocv.Net
+	// netLoaded is needed since methods of an uninitialized gocv.Net must not be called
+	netLoaded           bool
 	modelPath           string
+	preprocessor        preprocessing.Preprocessor
 }
 
 type Config struct {
@@ -38,10 +44,29 @@ type Config struct {
 	InputTag string //= "input"
 	/* the name with which the output node of a graph is labelled */
 	OutputTag string // = "resnet_v2_152/predictions/Reshape_1"
+	/* the name of the feature layer which is returned by ExtractFeatures (optional) */
+	FeatureTag string
 	/* the name of the file which contains the imagenet label mappings */
 	LabelFile string // = "imagenet_comp_graph_label_strings.txt"
+	/* the line of the label file which belongs to the first output of the model. 1 skips a "dummy" line,
+	   -1 adds a background label for models with a background class which is not in the label file */
+	LabelOffset int
+	/* the file with the WordNet ids of the labels in the same order as the label file (optional) */
+	WnidLabelFile string
 	/* the number of labels (or classes) this needs to be configurable since some models use 1000 and some 1001 */
 	NumLabels int
+	/* the name of the preprocessing which was used during training of the model (e.g. vgg or inception) */
+	Preprocessing string
+	/* the size of the images the model expects as input */
+	InputHeight int
+	InputWidth  int
+	/* whether the model outputs probabilities or logits (see the Output constants in imageClassifier) */
+	OutputType string
+	/* the temperature with which the output is normalized, see the calibrate command (0 means 1) */
+	Temperature float32
+	/* the test-time augmentation, the probabilities of the crops are averaged (see the Augmentation
+	   constants in preprocessing) */
+	Augmentation string
 
 	Backend gocv.NetBackendType
 	Target gocv.NetTargetType
@@ -61,6 +86,22 @@ func New(dataPathMapper func(string) string, log *logrus.Logger, config Config)
 		log.WithError(err).Errorln("could not load labels")
 	}
 
+	if config.WnidLabelFile != "" {
+		classifier.synsetIds, err = imageClassifier.LoadSynsetIds(dataPathMapper(config.WnidLabelFile))
+		if err != nil {
+			log.WithError(err).Errorln("could not load synset ids of labels")
+		}
+	}
+
+	// the labels and synset ids are aligned with the outputs of the model
+	classifier.synsetIds = imageClassifier.AlignSynsetIds(classifier.synsetIds, len(classifier.labels), config.LabelOffset)
+	classifier.labels = imageClassifier.ApplyLabelOffset(classifier.labels, config.LabelOffset, imageClassifier.BackgroundLabel)
+
+	classifier.preprocessor, err = preprocessing.New(config.Preprocessing, config.InputHeight, config.InputWidth)
+	if err != nil {
+		log.WithError(err).Errorln("could not create preprocessing")
+	}
+
 	return classifier
 }
 
@@ -92,64 +133,200 @@ func (gcvc *gocvTensorFlowClassifier) loadLabels() (err error) {
 func (gcvc *gocvTensorFlowClassifier) LoadFrozenModel(modelFile string) (err error) {
 	filepath := gcvc.dataPathForResource(modelFile)
 	gcvc.modelPath = filepath
-	/*gcvc.log.WithField("file", filepath).Infoln("loading frozen model")
-	gcvc.net = gocv.ReadNet(filepath, "")
-	err = gcvc.net.SetPreferableBackend(gcvc.config.Backend)
+
+	// the size of the output is only known after the first forward pass, see ClassifyImages
+	err = imageClassifier.VerifyLabelCount(gcvc.labels, gcvc.config.NumLabels)
+	if err == nil && gcvc.config.WnidLabelFile != "" {
+		err = imageClassifier.VerifySynsetIdCount(gcvc.synsetIds, gcvc.labels)
+	}
+	if err != nil {
+		gcvc.log.WithError(err).Errorln("labels do not match the model")
+		return err
+	}
+
+	gcvc.log.WithField("file", filepath).Infoln("loading frozen model")
+	net := gocv.ReadNet(filepath, "")
+	if net.Empty() {
+		err = errors.New(fmt.Sprintf("could not load frozen model %s", filepath))
+		gcvc.log.WithError(err).Errorln("error when reading tensorflow network")
+		return err
+	}
+
+	err = net.SetPreferableBackend(gcvc.config.Backend)
 	if err != nil {
 		gcvc.log.WithError(err).Errorln("error when setting backend for tensorflow network")
+		net.Close()
 		return err
 	}
-	err = gcvc.net.SetPreferableTarget(gcvc.config.Target)
+	err = net.SetPreferableTarget(gcvc.config.Target)
 	if err != nil {
-		gcvc.log.WithError(err).Errorln("error when setting targetl for tensorflow network")
+		gcvc.log.WithError(err).Errorln("error when setting target for tensorflow network")
+		net.Close()
 		return err
-	}*/
+	}
 
+	gcvc.Close()
+	gcvc.net = net
+	gcvc.netLoaded = true
 	return nil
 }
 
-func (gcvc *gocvTensorFlowClassifier) ClassifyImages(image []image.Image) ([][]tag.Tag, error) {
-	/*if gcvc.net.Empty() {
-		return nil, errors.New("no gocv net loaded")
-	}*/
+// Close releases the network. The classifier can not be used afterwards until a new model is loaded.
+func (gcvc *gocvTensorFlowClassifier) Close() error {
+	if !gcvc.netLoaded {
+		return nil
+	}
+	err := gcvc.net.Close()
+	gcvc.net = gocv.Net{}
+	gcvc.netLoaded = false
+	return err
+}
+
+func (gcvc *gocvTensorFlowClassifier) ClassifyImages(images []image.Image) ([][]tag.Tag, error) {
+	if !gcvc.netLoaded {
+		return nil, errors.New("model must be loaded before starting classification")
+	}
+
+	tags := make([][]tag.Tag, 0, len(images))
+	for _, curImage := range images {
+		cropPredictions, err := gcvc.getPredictionsForImage(curImage.GetFilename())
+		if err != nil {
+			// skip images which can not be loaded instead of failing the whole batch
+			curImage.SetError(err)
+			tags = append(tags, nil)
+			continue
+		}
+
+		for i, predictions := range cropPredictions {
+			if err := imageClassifier.VerifyOutputSize(len(predictions), gcvc.config.NumLabels); err != nil {
+				return nil, err
+			}
+			cropPredictions[i] = imageClassifier.ToProbabilities(gcvc.config.OutputType, gcvc.config.Temperature, predictions)
+		}
+
+		tags = append(tags, imageClassifier.PredictionsToTags(gcvc.labels, gcvc.synsetIds, imageClassifier.AveragePredictions(cropPredictions)))
+	}
+
+	return tags, nil
+}
+
+func (gcvc *gocvTensorFlowClassifier) ClassifyImagesTopK(images []image.Image, k int) ([][]tag.Tag, error) {
+	tags, err := gcvc.ClassifyImages(images)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range tags {
+		tags[i] = imageClassifier.TopKTags(tags[i], k)
+	}
+	return tags, nil
+}
+
+// getPredictionsForImage runs a forward pass of the network for each crop of a single image and
+// returns the values of the output layer. Without augmentation there is only one crop.
+func (gcvc *gocvTensorFlowClassifier) getPredictionsForImage(filename string) ([][]float32, error) {
+	gcvc.log.WithField("file", filename).Infoln("loading image")
+
+	img, err := preprocessing.LoadImage(filename)
+	if err != nil {
+		gcvc.log.WithField("file", filename).WithError(err).Errorln("error reading image")
+		return nil, err
+	}
 
-	gcvc.log.WithField("file", gcvc.modelPath).Infoln("loading frozen model")
-	net := gocv.ReadNet(gcvc.modelPath, "")
+	if gcvc.preprocessor == nil {
+		return nil, errors.New("no preprocessing configured")
+	}
 
-	for _, curImage := range image {
-		filename := curImage.GetFilename()
+	tensors, err := preprocessing.Augment(gcvc.preprocessor, img, gcvc.config.Augmentation)
+	if err != nil {
+		gcvc.log.WithError(err).Errorln("error during preprocessing of image")
+		return nil, err
+	}
 
-		// read image from file
-		img := gocv.IMRead(filename, gocv.IMReadColor)
-		if img.Empty() {
-			gcvc.log.Warn("error reading image")
+	predictions := make([][]float32, 0, len(tensors))
+	for _, tensor := range tensors {
+		cropPredictions, err := gcvc.forward(tensor, gcvc.config.OutputTag)
+		if err != nil {
+			return nil, err
 		}
+		predictions = append(predictions, cropPredictions)
+	}
+	return predictions, nil
+}
 
-		// convert to a 224x244 image blob that can be processed by Tensorflow
-		blob := gocv.BlobFromImage(img, 1.0, goimage.Pt(224, 244), gocv.NewScalar(0, 0, 0, 0), true, false)
-		//defer blob.Close()
+// forward runs the network on a preprocessed image and returns the flattened values of the given layer.
+func (gcvc *gocvTensorFlowClassifier) forward(tensor *preprocessing.Tensor, layer string) ([]float32, error) {
+	blob, err := gcvc.blobFromTensor(tensor)
+	if err != nil {
+		return nil, err
+	}
+	defer blob.Close()
+
+	// feed the blob into the classifier
+	gcvc.net.SetInput(blob, gcvc.config.InputTag)
 
-		// feed the blob into the classifier
-		net.SetInput(blob, gcvc.config.InputTag)
+	// run a forward pass thru the network
+	prob := gcvc.net.Forward(layer)
+	defer prob.Close()
 
-		// run a forward pass thru the network
-		prob := net.Forward(gcvc.config.OutputTag)
-		//defer prob.Close()
+	// reshape the results into a single row, e.g. 1xNumLabels for the output layer
+	probMat := prob.Reshape(1, 1)
+	defer probMat.Close()
 
-		// reshape the results into a 1x1000 matrix
-		probMat := prob.Reshape(1, 1)
-		//defer probMat.Close()
+	predictions := make([]float32, probMat.Cols())
+	for i := range predictions {
+		predictions[i] = probMat.GetFloatAt(0, i)
+	}
 
-		// determine the most probable classification, and display it
-		_, maxVal, _, maxLoc := gocv.MinMaxLoc(probMat)
-		fmt.Printf("maxLoc: %v, maxVal: %v\n", maxLoc, maxVal)
+	return predictions, nil
+}
 
-		gocv.WaitKey(1)
+// ExtractFeatures runs a forward pass up to the configured feature layer for each image. No
+// augmentation is applied, since it would mix the features of different crops.
+func (gcvc *gocvTensorFlowClassifier) ExtractFeatures(images []image.Image) ([][]float32, error) {
+	if gcvc.config.FeatureTag == "" {
+		return nil, errors.New("no feature layer configured for the classifier, set featureTag in its definition")
 	}
+	if !gcvc.netLoaded {
+		return nil, errors.New("model must be loaded before extracting features")
+	}
+	if gcvc.preprocessor == nil {
+		return nil, errors.New("no preprocessing configured")
+	}
+
+	features := make([][]float32, len(images))
+	for i, curImage := range images {
+		img, err := preprocessing.LoadImage(curImage.GetFilename())
+		if err != nil {
+			gcvc.log.WithField("file", curImage.GetFilename()).WithError(err).Errorln("error reading image")
+			curImage.SetError(err)
+			continue
+		}
 
-	return nil, nil
+		tensor, err := gcvc.preprocessor.Preprocess(img)
+		if err != nil {
+			curImage.SetError(err)
+			continue
+		}
+
+		features[i], err = gcvc.forward(tensor, gcvc.config.FeatureTag)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return features, nil
 }
 
-func (gcvc *gocvTensorFlowClassifier) ClassifyImagesTopK(image []image.Image, k int) ([][]tag.Tag, error) {
-	return gcvc.ClassifyImages(image)
+// blobFromTensor converts the preprocessed image into the input blob of the network. The
+// preprocessing is done in Go, so it is the same as for the TensorFlow backend.
+func (gcvc *gocvTensorFlowClassifier) blobFromTensor(tensor *preprocessing.Tensor) (gocv.Mat, error) {
+	mat, err := gocv.NewMatFromBytes(tensor.Height, tensor.Width, gocv.MatTypeCV32FC3, tensor.Bytes())
+	if err != nil {
+		return gocv.Mat{}, err
+	}
+	defer mat.Close()
+
+	// the tensor already has the size and values the model expects, BlobFromImage is only used to
+	// convert it from HWC into NCHW layout
+	return gocv.BlobFromImage(mat, 1, goimage.Pt(tensor.Width, tensor.Height), gocv.NewScalar(0, 0, 0, 0), false, false), nil
 }
@@ -0,0 +1,15 @@
+package gocvTfClassifier
+
+import (
+	"github.com/twatzl/imtag/tagger/image"
+	"strings"
+	"testing"
+)
+
+func TestGocvTensorFlowClassifier_ExtractFeaturesWithoutFeatureTag(t *testing.T) {
+	gcvc := &gocvTensorFlowClassifier{config: Config{}}
+	_, err := gcvc.ExtractFeatures([]image.Image{image.New("a.jpg")})
+	if err == nil || !strings.Contains(err.Error(), "featureTag") {
+		t.Errorf("expected error about missing featureTag but got %v", err)
+	}
+}
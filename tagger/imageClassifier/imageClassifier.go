@@ -28,3 +28,14 @@ type ImageClassifier interface {
 	// during the classification process.
 	ClassifyImagesTopK(image []image.Image, k int) ([][]tag.Tag, error)
 }
+
+// FeatureExtractor is implemented by classifiers which can return the activations of a feature layer
+// of their model, usually the layer before the classification (e.g. fc7 of VGG or pool5 of ResNet).
+// Unlike the tags these vectors describe how an image looks, so they can be used to find visually
+// similar images or near-duplicates.
+type FeatureExtractor interface {
+	// ExtractFeatures returns the flattened feature vector of each image. Images which can not be
+	// loaded get an error and a nil vector. An error is returned if the classifier has no feature
+	// layer configured.
+	ExtractFeatures(image []image.Image) ([][]float32, error)
+}
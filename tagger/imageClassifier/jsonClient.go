@@ -0,0 +1,113 @@
+package imageClassifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// Settings of a JSONClient which are used if none are configured.
+const (
+	DefaultHTTPTimeout = 30 * time.Second
+	DefaultHTTPRetries = 2
+	retryDelay         = 500 * time.Millisecond
+)
+
+// Retries returns how often a failed request of a classifier is repeated. If the number is not
+// configured the default of the backend is used, 0 or a negative number disables retries.
+func Retries(configured *int, defaultRetries int) int {
+	if configured == nil {
+		return defaultRetries
+	}
+	if *configured < 0 {
+		return 0
+	}
+	return *configured
+}
+
+// JSONClient sends requests with a JSON body to a classification server and decodes its JSON
+// responses. Network errors and server errors (5xx) are retried with an increasing delay, client
+// errors (4xx) are not since repeating the request would not change the result.
+type JSONClient struct {
+	log     *logrus.Logger
+	client  *http.Client
+	retries int
+}
+
+// NewJSONClient creates a client with the timeout of a single request (DefaultHTTPTimeout if it is 0)
+// and the configured number of retries (see Retries, default is DefaultHTTPRetries).
+func NewJSONClient(log *logrus.Logger, timeout time.Duration, retries *int) *JSONClient {
+	if timeout <= 0 {
+		timeout = DefaultHTTPTimeout
+	}
+
+	return &JSONClient{
+		log:     log,
+		client:  &http.Client{Timeout: timeout},
+		retries: Retries(retries, DefaultHTTPRetries),
+	}
+}
+
+// Post sends req to the url and decodes the response into resp.
+func (c *JSONClient) Post(url string, req interface{}, resp interface{}) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= c.retries; attempt++ {
+		if attempt > 0 {
+			c.log.WithField("url", url).WithField("attempt", attempt).WithError(lastErr).Warnln("retrying request")
+			time.Sleep(time.Duration(attempt) * retryDelay)
+		}
+
+		retry, err := c.post(url, body, resp)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retry {
+			break
+		}
+	}
+
+	c.log.WithField("url", url).WithError(lastErr).Errorln("request failed")
+	return lastErr
+}
+
+func (c *JSONClient) post(url string, body []byte, resp interface{}) (retry bool, err error) {
+	httpResp, err := c.client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return true, err
+	}
+	defer httpResp.Body.Close()
+
+	data, err := ioutil.ReadAll(httpResp.Body)
+	if err != nil {
+		return true, err
+	}
+
+	if httpResp.StatusCode != http.StatusOK {
+		err = errors.New(fmt.Sprintf("server responded with %s: %s", httpResp.Status, errorMessage(data)))
+		return httpResp.StatusCode >= 500, err
+	}
+	return false, json.Unmarshal(data, resp)
+}
+
+// errorMessage returns the error field of a JSON error response (e.g. of tensorflow serving) or the
+// whole body if it has none.
+func errorMessage(data []byte) string {
+	var resp struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(data, &resp) != nil || resp.Error == "" {
+		return string(data)
+	}
+	return resp.Error
+}
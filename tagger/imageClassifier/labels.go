@@ -0,0 +1,106 @@
+package imageClassifier
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// LoadLabels reads the labels of a classifier from a text file with one label per line. The line
+// number is the index of the label in the output of the model.
+func LoadLabels(filename string) ([]string, error) {
+	labelsFile, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer labelsFile.Close()
+
+	scanner := bufio.NewScanner(labelsFile)
+	labels := []string{}
+	for scanner.Scan() {
+		labels = append(labels, scanner.Text())
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+// LoadSynsetIds reads the WordNet ids of the labels of a classifier from a text file. The id is the
+// first word of each line, so both plain id lists and files like synset_words.txt
+// ("n01440764 tench, Tinca tinca") can be used. Empty lines have no synset (e.g. a background class).
+func LoadSynsetIds(filename string) ([]string, error) {
+	lines, err := LoadLabels(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(lines))
+	for i, line := range lines {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			ids[i] = fields[0]
+		}
+	}
+	return ids, nil
+}
+
+// BackgroundLabel is the label of outputs of a model which have no line in the label file.
+const BackgroundLabel = "background"
+
+// ApplyLabelOffset aligns the lines of a label file with the outputs of a model, the output i gets the
+// line i+offset. A positive offset skips lines, e.g. the "dummy" line of label files made for models
+// with a background class, when they are used with a model without one. A negative offset is needed
+// for models with a background class whose label file has none, the first outputs then get fill.
+func ApplyLabelOffset(lines []string, offset int, fill string) []string {
+	if offset >= 0 {
+		if offset > len(lines) {
+			return []string{}
+		}
+		return lines[offset:]
+	}
+
+	padded := make([]string, -offset, len(lines)-offset)
+	for i := range padded {
+		padded[i] = fill
+	}
+	return append(padded, lines...)
+}
+
+// AlignSynsetIds aligns the lines of a wnid label file with the outputs of a model. The offset of the
+// label file is only applied if the wnid file has as many lines as the label file. Other files, like
+// synset_words.txt which has no line for a background class, must have exactly one line per output.
+func AlignSynsetIds(ids []string, labelLines int, offset int) []string {
+	if len(ids) != labelLines {
+		return ids
+	}
+	return ApplyLabelOffset(ids, offset, "")
+}
+
+// VerifySynsetIdCount checks that there is exactly one WordNet id for each label.
+func VerifySynsetIdCount(ids []string, labels []string) error {
+	if len(ids) != len(labels) {
+		return errors.New(fmt.Sprintf("there are %d labels but %d synset ids, check wnidLabelFile and labelOffset",
+			len(labels), len(ids)))
+	}
+	return nil
+}
+
+// VerifyLabelCount checks that there is exactly one label for each output of the model.
+func VerifyLabelCount(labels []string, numLabels int) error {
+	if len(labels) != numLabels {
+		return errors.New(fmt.Sprintf("the model has %d outputs but there are %d labels, check numLabels, labelFile and labelOffset",
+			numLabels, len(labels)))
+	}
+	return nil
+}
+
+// VerifyOutputSize checks that the number of outputs of a model matches the configured number of labels.
+func VerifyOutputSize(outputSize int, numLabels int) error {
+	if outputSize != numLabels {
+		return errors.New(fmt.Sprintf("the model outputs %d classes but numLabels is %d", outputSize, numLabels))
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package imageClassifier
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestApplyLabelOffset(t *testing.T) {
+	lines := []string{"dummy", "tench", "goldfish"}
+
+	tests := []struct {
+		offset   int
+		expected []string
+	}{
+		{0, []string{"dummy", "tench", "goldfish"}},
+		{1, []string{"tench", "goldfish"}},
+		{-1, []string{BackgroundLabel, "dummy", "tench", "goldfish"}},
+		{5, []string{}},
+	}
+
+	for _, test := range tests {
+		got := ApplyLabelOffset(lines, test.offset, BackgroundLabel)
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("offset %d: expected %v but got %v", test.offset, test.expected, got)
+		}
+	}
+}
+
+func TestVerifyLabelCount(t *testing.T) {
+	if err := VerifyLabelCount([]string{"tench", "goldfish"}, 2); err != nil {
+		t.Errorf("expected no error but got %v", err)
+	}
+	if err := VerifyLabelCount([]string{"dummy", "tench", "goldfish"}, 2); err == nil {
+		t.Errorf("expected an error for too many labels")
+	}
+}
+
+func TestAlignSynsetIds(t *testing.T) {
+	// imagenet_comp_graph_label_strings.txt has a dummy line, synset_words.txt has only the 1000 classes
+	labelLines := make([]string, 1001)
+	labelLines[0] = "dummy"
+	synsetWords := make([]string, 1000)
+	for i := range synsetWords {
+		labelLines[i+1] = fmt.Sprintf("label %d", i)
+		synsetWords[i] = fmt.Sprintf("n%08d", i)
+	}
+
+	// model with 1000 outputs, only the label file is shifted
+	labels := ApplyLabelOffset(labelLines, 1, BackgroundLabel)
+	ids := AlignSynsetIds(synsetWords, len(labelLines), 1)
+	if err := VerifySynsetIdCount(ids, labels); err != nil {
+		t.Fatal(err)
+	}
+	if ids[0] != "n00000000" || ids[999] != "n00000999" {
+		t.Errorf("expected synset ids to stay aligned with the labels but got %s and %s", ids[0], ids[999])
+	}
+
+	// model with 1001 outputs, there is no synset id for the dummy label
+	labels = ApplyLabelOffset(labelLines, 0, BackgroundLabel)
+	ids = AlignSynsetIds(synsetWords, len(labelLines), 0)
+	if err := VerifySynsetIdCount(ids, labels); err == nil {
+		t.Errorf("expected an error for 1000 synset ids and 1001 labels")
+	}
+
+	// a wnid file with the layout of the label file is shifted like the label file
+	wnids := append([]string{""}, synsetWords...)
+	ids = AlignSynsetIds(wnids, len(labelLines), 1)
+	if len(ids) != 1000 || ids[0] != "n00000000" {
+		t.Errorf("expected offset to be applied to wnid file with the layout of the label file")
+	}
+}
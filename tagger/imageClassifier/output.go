@@ -0,0 +1,97 @@
+package imageClassifier
+
+import "math"
+
+// Types of values a model can output. Tags have to carry probabilities, so the output of models
+// which return logits has to be normalized first.
+const (
+	// OutputProbabilities means the model already applies a softmax to its output.
+	OutputProbabilities = "probabilities"
+	// OutputLogits means the model returns the raw, unnormalized scores of its last layer.
+	OutputLogits = "logits"
+)
+
+// defaultTemperature does not change the output of a model.
+const defaultTemperature = 1
+
+// NeedsSoftmax tells whether the output of a model has to be normalized with a softmax before it can
+// be used as probabilities. This is the case for logits and whenever a temperature is set.
+func NeedsSoftmax(outputType string, temperature float32) bool {
+	return outputType == OutputLogits || (temperature != 0 && temperature != defaultTemperature)
+}
+
+// ToProbabilities converts the output of a model into probabilities. Logits are divided by the
+// temperature and normalized with a softmax. Probabilities are only changed if a temperature is set,
+// in this case their logarithm is used as logits. A temperature of 0 is the same as 1.
+func ToProbabilities(outputType string, temperature float32, values []float32) []float32 {
+	if !NeedsSoftmax(outputType, temperature) {
+		return values
+	}
+
+	logits := values
+	if outputType != OutputLogits {
+		logits = make([]float32, len(values))
+		for i, p := range values {
+			logits[i] = float32(math.Log(math.Max(float64(p), minProbability)))
+		}
+	}
+	return Softmax(logits, temperature)
+}
+
+// minProbability avoids taking the logarithm of 0.
+const minProbability = 1e-30
+
+// Softmax normalizes the logits divided by the temperature so they sum up to 1. A temperature
+// greater than 1 flattens the distribution, a temperature smaller than 1 sharpens it.
+func Softmax(logits []float32, temperature float32) []float32 {
+	if temperature == 0 {
+		temperature = defaultTemperature
+	}
+
+	// subtracting the maximum does not change the result but avoids overflows of exp
+	max := math.Inf(-1)
+	for _, l := range logits {
+		max = math.Max(max, float64(l))
+	}
+
+	exps := make([]float64, len(logits))
+	sum := 0.0
+	for i, l := range logits {
+		exps[i] = math.Exp((float64(l) - max) / float64(temperature))
+		sum += exps[i]
+	}
+
+	probabilities := make([]float32, len(logits))
+	for i, e := range exps {
+		probabilities[i] = float32(e / sum)
+	}
+	return probabilities
+}
+
+// AveragePredictions returns the mean of the probability vectors of several crops of an image, see
+// the augmentations of the preprocessing package.
+func AveragePredictions(predictions [][]float32) []float32 {
+	if len(predictions) == 1 {
+		return predictions[0]
+	}
+
+	mean := make([]float32, len(predictions[0]))
+	for _, prediction := range predictions {
+		for i, p := range prediction {
+			mean[i] += p / float32(len(predictions))
+		}
+	}
+	return mean
+}
+
+// AverageCrops averages the predictions of consecutive crops. crops holds the number of crops of
+// each image, the result has one prediction per image.
+func AverageCrops(predictions [][]float32, crops []int) [][]float32 {
+	averaged := make([][]float32, 0, len(crops))
+	start := 0
+	for _, n := range crops {
+		averaged = append(averaged, AveragePredictions(predictions[start:start+n]))
+		start += n
+	}
+	return averaged
+}
@@ -0,0 +1,79 @@
+package imageClassifier
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSoftmax(t *testing.T) {
+	probabilities := Softmax([]float32{1, 2, 3}, 1)
+
+	expected := []float64{0.0900306, 0.2447285, 0.6652410}
+	for i, p := range probabilities {
+		if math.Abs(float64(p)-expected[i]) > 1e-6 {
+			t.Errorf("probability %d is %f, expected %f", i, p, expected[i])
+		}
+	}
+}
+
+func TestSoftmax_Temperature(t *testing.T) {
+	sharp := Softmax([]float32{1, 2, 3}, 1)
+	flat := Softmax([]float32{1, 2, 3}, 10)
+
+	if flat[2] >= sharp[2] {
+		t.Errorf("higher temperature must flatten the distribution, got %f and %f", flat[2], sharp[2])
+	}
+}
+
+func TestSoftmax_LargeLogits(t *testing.T) {
+	probabilities := Softmax([]float32{1000, 1000}, 1)
+
+	if probabilities[0] != 0.5 || probabilities[1] != 0.5 {
+		t.Errorf("expected equal probabilities, got %v", probabilities)
+	}
+}
+
+func TestToProbabilities(t *testing.T) {
+	values := []float32{0.2, 0.8}
+	if p := ToProbabilities(OutputProbabilities, 0, values); p[0] != 0.2 || p[1] != 0.8 {
+		t.Errorf("probabilities without temperature must not change, got %v", p)
+	}
+
+	p := ToProbabilities(OutputProbabilities, 1000, values)
+	if math.Abs(float64(p[0]-p[1])) > 1e-2 {
+		t.Errorf("a high temperature must flatten the probabilities, got %v", p)
+	}
+
+	p = ToProbabilities(OutputLogits, 0, []float32{-3, 5})
+	if math.Abs(float64(p[0]+p[1])-1) > 1e-6 {
+		t.Errorf("logits must be normalized, got %v", p)
+	}
+}
+
+func TestFitTemperature(t *testing.T) {
+	// the classifier is sure about class 0, but it is only correct for 60% of the images
+	probabilities := [][]float32{}
+	targets := []int{}
+	for i := 0; i < 10; i++ {
+		probabilities = append(probabilities, []float32{0.99, 0.01})
+		if i < 6 {
+			targets = append(targets, 0)
+		} else {
+			targets = append(targets, 1)
+		}
+	}
+
+	temperature, before, after, err := FitTemperature(probabilities, targets)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// softmax(log(p) / T)[0] = 0.6
+	expected := math.Log(99) / math.Log(1.5)
+	if math.Abs(float64(temperature)-expected)/expected > 1e-2 {
+		t.Errorf("temperature is %f, expected %f", temperature, expected)
+	}
+	if after >= before {
+		t.Errorf("calibration must reduce the negative log likelihood, before %f after %f", before, after)
+	}
+}
@@ -0,0 +1,44 @@
+package imageClassifier
+
+import (
+	"github.com/twatzl/imtag/tagger/tag"
+	"sort"
+)
+
+// PredictionsToTags creates a tag for each prediction of a classifier using the label and synset id
+// with the same index. Predictions for which no label exists are skipped, the synset ids are optional.
+func PredictionsToTags(labels []string, synsetIds []string, predictions []float32) []tag.Tag {
+	tags := make([]tag.Tag, 0, len(predictions))
+	for i, p := range predictions {
+		if i >= len(labels) {
+			break
+		}
+		tags = append(tags, NewTag(labels, synsetIds, i, p))
+	}
+	return tags
+}
+
+// NewTag creates the tag for the label with the given index. If there is a synset id for the label
+// it is added to the tag.
+func NewTag(labels []string, synsetIds []string, index int, confidence float32) tag.Tag {
+	if index < len(synsetIds) && synsetIds[index] != "" {
+		return tag.NewWithSynset(labels[index], synsetIds[index], confidence)
+	}
+	return tag.New(labels[index], confidence)
+}
+
+// TopKTags returns the k tags with the highest confidence sorted by confidence. This can be
+// used by classifiers which have no efficient way of computing the top k results themselves.
+func TopKTags(tags []tag.Tag, k int) []tag.Tag {
+	sorted := make([]tag.Tag, len(tags))
+	copy(sorted, tags)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].GetConfidence() > sorted[j].GetConfidence()
+	})
+
+	if k < len(sorted) {
+		sorted = sorted[:k]
+	}
+	return sorted
+}
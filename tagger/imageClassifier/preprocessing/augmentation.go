@@ -0,0 +1,125 @@
+package preprocessing
+
+import (
+	"errors"
+	"fmt"
+	"image"
+)
+
+// Test-time augmentations which can be configured for a classifier. The model is run on several
+// crops of an image and the probabilities of the crops are averaged.
+const (
+	// AugmentationNone only uses the central crop.
+	AugmentationNone = "none"
+	// AugmentationFiveCrop uses the central crop and the crops at the four corners.
+	AugmentationFiveCrop = "fiveCrop"
+	// AugmentationTenCrop uses the five crops and their horizontal mirror images.
+	AugmentationTenCrop = "tenCrop"
+)
+
+// CropPosition is the position of a crop in the image.
+type CropPosition int
+
+const (
+	CropCenter CropPosition = iota
+	CropTopLeft
+	CropTopRight
+	CropBottomLeft
+	CropBottomRight
+)
+
+var fiveCropPositions = []CropPosition{CropCenter, CropTopLeft, CropTopRight, CropBottomLeft, CropBottomRight}
+
+// stagedPreprocessor is implemented by all preprocessings, so the crops for the augmentation are
+// taken the same way as the central crop.
+type stagedPreprocessor interface {
+	// prepare converts the image into the tensor from which the crops are taken
+	prepare(img image.Image) *Tensor
+	// cropAt takes the crop at the given position and converts it into the input of the model
+	cropAt(t *Tensor, position CropPosition) (*Tensor, error)
+}
+
+// VerifyAugmentation checks if the name is one of the known augmentations. An empty name is the
+// same as AugmentationNone.
+func VerifyAugmentation(augmentation string) error {
+	switch augmentation {
+	case "", AugmentationNone, AugmentationFiveCrop, AugmentationTenCrop:
+		return nil
+	}
+	return errors.New(fmt.Sprintf("unknown augmentation %s, must be %s, %s or %s",
+		augmentation, AugmentationNone, AugmentationFiveCrop, AugmentationTenCrop))
+}
+
+// NumCrops returns the number of tensors which Augment creates for an image.
+func NumCrops(augmentation string) int {
+	switch augmentation {
+	case AugmentationFiveCrop:
+		return 5
+	case AugmentationTenCrop:
+		return 10
+	}
+	return 1
+}
+
+// Augment preprocesses the image once for every crop of the augmentation. The first tensor is
+// always the one created by Preprocess.
+func Augment(p Preprocessor, img image.Image, augmentation string) ([]*Tensor, error) {
+	if err := VerifyAugmentation(augmentation); err != nil {
+		return nil, err
+	}
+
+	staged, ok := p.(stagedPreprocessor)
+	if !ok || NumCrops(augmentation) == 1 {
+		t, err := p.Preprocess(img)
+		if err != nil {
+			return nil, err
+		}
+		return []*Tensor{t}, nil
+	}
+
+	prepared := staged.prepare(img)
+	tensors := make([]*Tensor, 0, NumCrops(augmentation))
+	for _, position := range fiveCropPositions {
+		t, err := staged.cropAt(prepared, position)
+		if err != nil {
+			return nil, err
+		}
+		tensors = append(tensors, t)
+	}
+
+	if augmentation == AugmentationTenCrop {
+		for i := 0; i < 5; i++ {
+			tensors = append(tensors, FlipHorizontal(tensors[i]))
+		}
+	}
+	return tensors, nil
+}
+
+// CropAt returns the region of the given size at the position in the tensor.
+func CropAt(t *Tensor, position CropPosition, height, width int) (*Tensor, error) {
+	offsetHeight, offsetWidth := (t.Height-height)/2, (t.Width-width)/2
+	switch position {
+	case CropTopLeft:
+		offsetHeight, offsetWidth = 0, 0
+	case CropTopRight:
+		offsetHeight, offsetWidth = 0, t.Width-width
+	case CropBottomLeft:
+		offsetHeight, offsetWidth = t.Height-height, 0
+	case CropBottomRight:
+		offsetHeight, offsetWidth = t.Height-height, t.Width-width
+	}
+	return Crop(t, offsetHeight, offsetWidth, height, width)
+}
+
+// FlipHorizontal returns the mirror image of the tensor.
+func FlipHorizontal(t *Tensor) *Tensor {
+	flipped := NewTensor(t.Height, t.Width, t.Channels)
+	for y := 0; y < t.Height; y++ {
+		for x := 0; x < t.Width; x++ {
+			for c := 0; c < t.Channels; c++ {
+				flipped.Set(y, t.Width-1-x, c, t.At(y, x, c))
+			}
+		}
+	}
+	return flipped
+}
@@ -0,0 +1,43 @@
+package preprocessing
+
+import "image"
+
+// based on https://github.com/tensorflow/models/blob/master/research/slim/preprocessing/inception_preprocessing.py
+
+const centralFraction = 0.875
+
+type inceptionPreprocessor struct {
+	height, width   int
+	centralFraction float64
+}
+
+func (p *inceptionPreprocessor) Size() (int, int) {
+	return p.height, p.width
+}
+
+func (p *inceptionPreprocessor) Preprocess(img image.Image) (*Tensor, error) {
+	return p.cropAt(p.prepare(img), CropCenter)
+}
+
+func (p *inceptionPreprocessor) prepare(img image.Image) *Tensor {
+	return FromImage(img)
+}
+
+// cropAt takes a crop of the central fraction of the image at the given position and resizes it.
+func (p *inceptionPreprocessor) cropAt(t *Tensor, position CropPosition) (*Tensor, error) {
+	// same computation as tf.image.central_crop
+	offsetHeight := int((float64(t.Height) - float64(t.Height)*p.centralFraction) / 2)
+	offsetWidth := int((float64(t.Width) - float64(t.Width)*p.centralFraction) / 2)
+	t, err := CropAt(t, position, t.Height-2*offsetHeight, t.Width-2*offsetWidth)
+	if err != nil {
+		return nil, err
+	}
+
+	t = ResizeBilinear(t, p.height, p.width)
+
+	// convert to [0, 1] and scale to [-1, 1]
+	for i, v := range t.Data {
+		t.Data[i] = (v/255 - 0.5) * 2
+	}
+	return t, nil
+}
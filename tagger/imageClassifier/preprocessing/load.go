@@ -0,0 +1,65 @@
+package preprocessing
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+
+	"github.com/rwcarlsen/goexif/exif"
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+)
+
+// LoadImage reads and decodes the image file with the given name. JPEG, PNG, GIF, WebP, BMP and
+// TIFF files are supported. If the file contains an EXIF orientation the image is rotated
+// accordingly, so it is classified the way it is displayed.
+func LoadImage(filename string) (image.Image, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, format, err := image.Decode(file)
+	if err == image.ErrFormat {
+		return nil, errors.New(fmt.Sprintf("unsupported image format of file %s", filename))
+	} else if err != nil {
+		return nil, errors.New(fmt.Sprintf("could not decode %s: %s", filename, err.Error()))
+	}
+
+	// only jpeg and tiff files contain exif data
+	if format != "jpeg" && format != "tiff" {
+		return img, nil
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return ApplyOrientation(img, readOrientation(file)), nil
+}
+
+// readOrientation returns the EXIF orientation of the image or 1 (no transformation) if the
+// orientation can not be read.
+func readOrientation(r io.Reader) int {
+	x, err := exif.Decode(r)
+	if err != nil {
+		return 1
+	}
+
+	orientationTag, err := x.Get(exif.Orientation)
+	if err != nil {
+		return 1
+	}
+
+	orientation, err := orientationTag.Int(0)
+	if err != nil {
+		return 1
+	}
+	return orientation
+}
@@ -0,0 +1,54 @@
+package preprocessing
+
+import (
+	"image"
+	"image/draw"
+)
+
+// ApplyOrientation transforms the image according to the value of the EXIF orientation tag. The
+// values are defined in the EXIF specification, 1 means the image is stored the way it should be
+// displayed. Unknown values leave the image unchanged.
+func ApplyOrientation(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	src := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(src, src.Rect, img, bounds.Min, draw.Src)
+
+	w, h := bounds.Dx(), bounds.Dy()
+	var dst *image.RGBA
+	if orientation >= 5 {
+		// orientations 5 to 8 rotate by 90 degrees, so height and width are swapped
+		dst = image.NewRGBA(image.Rect(0, 0, h, w))
+	} else {
+		dst = image.NewRGBA(image.Rect(0, 0, w, h))
+	}
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored horizontally
+				dx, dy = w-1-x, y
+			case 3: // rotated by 180 degrees
+				dx, dy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, h-1-y
+			case 5: // mirrored along the top left to bottom right diagonal
+				dx, dy = y, x
+			case 6: // rotated by 90 degrees clockwise
+				dx, dy = h-1-y, x
+			case 7: // mirrored along the top right to bottom left diagonal
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotated by 90 degrees counter clockwise
+				dx, dy = y, w-1-x
+			}
+
+			copy(dst.Pix[dst.PixOffset(dx, dy):dst.PixOffset(dx, dy)+4], src.Pix[src.PixOffset(x, y):src.PixOffset(x, y)+4])
+		}
+	}
+
+	return dst
+}
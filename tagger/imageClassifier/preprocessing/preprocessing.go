@@ -0,0 +1,191 @@
+// The package preprocessing converts images into the input tensors of image classifiers.
+// The preprocessing is done in pure Go, so it can be shared between the different classifier
+// backends and does not need a TensorFlow graph per image. The implementations follow the
+// evaluation preprocessing of the TensorFlow slim models:
+// https://github.com/tensorflow/models/tree/master/research/slim/preprocessing
+package preprocessing
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+)
+
+// Names of the preprocessing pipelines which can be configured for a classifier. The classifiers
+// have to apply the same preprocessing to an image that was used during training of the model.
+const (
+	// VGG resizes the smaller side of the image to 256 pixels, takes a central crop
+	// and subtracts the mean of the ImageNet training data.
+	VGG = "vgg"
+	// Inception takes a central crop of 87.5% of the image, resizes it and scales
+	// the values to [-1, 1]. This is used by Inception and ResNet V2 models.
+	Inception = "inception"
+	// Resize only resizes the image without any normalization of the values.
+	Resize = "resize"
+)
+
+const defaultInputSize = 224
+
+// Tensor holds the values of an image in height, width, channel (HWC) order.
+type Tensor struct {
+	Height   int
+	Width    int
+	Channels int
+	Data     []float32
+}
+
+// Preprocessor converts an image into the input tensor of a model.
+type Preprocessor interface {
+	Preprocess(img image.Image) (*Tensor, error)
+	// Size returns the height and width of the tensors created by the preprocessing.
+	Size() (height, width int)
+}
+
+// New returns the preprocessing with the given name. If height or width are 0 the default
+// input size of 224 is used.
+func New(name string, height, width int) (Preprocessor, error) {
+	if height <= 0 {
+		height = defaultInputSize
+	}
+	if width <= 0 {
+		width = defaultInputSize
+	}
+
+	switch name {
+	case VGG, "":
+		// the smaller side is resized to 256, larger inputs need a larger side to take the crop from
+		return &vggPreprocessor{height, width, maxInt(resizeSideMin, maxInt(height, width))}, nil
+	case Inception:
+		return &inceptionPreprocessor{height, width, centralFraction}, nil
+	case Resize:
+		return &resizePreprocessor{height, width}, nil
+	}
+
+	return nil, errors.New(fmt.Sprintf("unknown preprocessing %s", name))
+}
+
+// NewTensor creates a tensor with all values set to 0.
+func NewTensor(height, width, channels int) *Tensor {
+	return &Tensor{
+		Height:   height,
+		Width:    width,
+		Channels: channels,
+		Data:     make([]float32, height*width*channels),
+	}
+}
+
+// At returns the value at the given position.
+func (t *Tensor) At(y, x, c int) float32 {
+	return t.Data[(y*t.Width+x)*t.Channels+c]
+}
+
+// Set sets the value at the given position.
+func (t *Tensor) Set(y, x, c int, value float32) {
+	t.Data[(y*t.Width+x)*t.Channels+c] = value
+}
+
+// FromImage converts an image to a tensor with 3 channels (RGB) and values in [0, 255].
+func FromImage(img image.Image) *Tensor {
+	rgba, ok := img.(*image.RGBA)
+	if !ok {
+		// draw has fast paths for the common image types, which is a lot faster than
+		// converting every pixel to color.Color
+		rgba = image.NewRGBA(img.Bounds())
+		draw.Draw(rgba, rgba.Rect, img, img.Bounds().Min, draw.Src)
+	}
+
+	bounds := rgba.Bounds()
+	t := NewTensor(bounds.Dy(), bounds.Dx(), 3)
+	i := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		offset := rgba.PixOffset(bounds.Min.X, y)
+		for x := 0; x < bounds.Dx(); x++ {
+			// drop the alpha channel
+			t.Data[i] = float32(rgba.Pix[offset])
+			t.Data[i+1] = float32(rgba.Pix[offset+1])
+			t.Data[i+2] = float32(rgba.Pix[offset+2])
+			i += 3
+			offset += 4
+		}
+	}
+	return t
+}
+
+// Bytes returns the values of the tensor as little endian float32 values, which is the memory
+// layout expected by TensorFlow and OpenCV.
+func (t *Tensor) Bytes() []byte {
+	buf := make([]byte, 4*len(t.Data))
+	for i, v := range t.Data {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(v))
+	}
+	return buf
+}
+
+// ResizeBilinear resizes the tensor with bilinear interpolation. The result is the same as
+// tf.image.resize_bilinear with align_corners=False.
+func ResizeBilinear(t *Tensor, height, width int) *Tensor {
+	resized := NewTensor(height, width, t.Channels)
+	scaleY := float32(t.Height) / float32(height)
+	scaleX := float32(t.Width) / float32(width)
+
+	for y := 0; y < height; y++ {
+		inY := float32(y) * scaleY
+		y0 := int(inY)
+		y1 := minInt(y0+1, t.Height-1)
+		dy := inY - float32(y0)
+
+		for x := 0; x < width; x++ {
+			inX := float32(x) * scaleX
+			x0 := int(inX)
+			x1 := minInt(x0+1, t.Width-1)
+			dx := inX - float32(x0)
+
+			for c := 0; c < t.Channels; c++ {
+				top := t.At(y0, x0, c) + (t.At(y0, x1, c)-t.At(y0, x0, c))*dx
+				bottom := t.At(y1, x0, c) + (t.At(y1, x1, c)-t.At(y1, x0, c))*dx
+				resized.Set(y, x, c, top+(bottom-top)*dy)
+			}
+		}
+	}
+
+	return resized
+}
+
+// Crop returns the region of the tensor with the given offset and size.
+func Crop(t *Tensor, offsetHeight, offsetWidth, height, width int) (*Tensor, error) {
+	if offsetHeight < 0 || offsetWidth < 0 || offsetHeight+height > t.Height || offsetWidth+width > t.Width {
+		return nil, errors.New(fmt.Sprintf("crop of size %dx%d at %d,%d exceeds image size %dx%d",
+			height, width, offsetHeight, offsetWidth, t.Height, t.Width))
+	}
+
+	cropped := NewTensor(height, width, t.Channels)
+	rowLength := width * t.Channels
+	for y := 0; y < height; y++ {
+		start := ((offsetHeight+y)*t.Width + offsetWidth) * t.Channels
+		copy(cropped.Data[y*rowLength:(y+1)*rowLength], t.Data[start:start+rowLength])
+	}
+
+	return cropped, nil
+}
+
+// CentralCrop returns the central region of the tensor with the given size.
+func CentralCrop(t *Tensor, height, width int) (*Tensor, error) {
+	return Crop(t, (t.Height-height)/2, (t.Width-width)/2, height, width)
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,157 @@
+package preprocessing
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestResizeBilinear(t *testing.T) {
+	input := &Tensor{Height: 2, Width: 2, Channels: 1, Data: []float32{0, 2, 4, 6}}
+
+	resized := ResizeBilinear(input, 4, 4)
+
+	// align_corners=False maps output pixel x to input pixel x*in/out and clamps at the border
+	expected := []float32{
+		0, 1, 2, 2,
+		2, 3, 4, 4,
+		4, 5, 6, 6,
+		4, 5, 6, 6,
+	}
+	for i := range expected {
+		if resized.Data[i] != expected[i] {
+			t.Fatalf("expected %v but got %v", expected, resized.Data)
+		}
+	}
+}
+
+func TestVGGPreprocessing(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 400, 300))
+	for y := 0; y < 300; y++ {
+		for x := 0; x < 400; x++ {
+			img.Set(x, y, color.RGBA{200, 150, 100, 255})
+		}
+	}
+
+	p, err := New(VGG, 224, 224)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tensor, err := p.Preprocess(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tensor.Height != 224 || tensor.Width != 224 || tensor.Channels != 3 {
+		t.Fatalf("unexpected shape %dx%dx%d", tensor.Height, tensor.Width, tensor.Channels)
+	}
+
+	expected := []float32{200 - R_MEAN, 150 - G_MEAN, 100 - B_MEAN}
+	for i, v := range tensor.Data {
+		if math.Abs(float64(v-expected[i%3])) > 1e-4 {
+			t.Fatalf("expected %v at channel %d but got %v", expected[i%3], i%3, v)
+		}
+	}
+}
+
+// inputs larger than 256 pixels must not fail because the resized image is smaller than the crop
+func TestVGGPreprocessing_LargeInput(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 400, 300))
+
+	for _, size := range [][2]int{{299, 299}, {320, 320}, {224, 320}} {
+		p, err := New(VGG, size[0], size[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tensor, err := p.Preprocess(img)
+		if err != nil {
+			t.Fatalf("preprocessing for %dx%d failed: %s", size[0], size[1], err.Error())
+		}
+		if tensor.Height != size[0] || tensor.Width != size[1] {
+			t.Errorf("expected %dx%d but got %dx%d", size[0], size[1], tensor.Height, tensor.Width)
+		}
+	}
+}
+
+func TestSmallestSizeAtLeast(t *testing.T) {
+	height, width := smallestSizeAtLeast(300, 400, 256)
+	if height != 256 || width != 341 {
+		t.Errorf("expected 256x341 but got %dx%d", height, width)
+	}
+}
+
+func TestApplyOrientation(t *testing.T) {
+	// 2x1 image with a red pixel on the left and a blue pixel on the right
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	red := color.RGBA{255, 0, 0, 255}
+	blue := color.RGBA{0, 0, 255, 255}
+	img.Set(0, 0, red)
+	img.Set(1, 0, blue)
+
+	// rotated clockwise the left pixel ends up on top
+	rotated := ApplyOrientation(img, 6)
+	if rotated.Bounds().Dx() != 1 || rotated.Bounds().Dy() != 2 {
+		t.Fatalf("unexpected size %v", rotated.Bounds())
+	}
+	if rotated.At(0, 0) != red || rotated.At(0, 1) != blue {
+		t.Errorf("unexpected pixels %v %v", rotated.At(0, 0), rotated.At(0, 1))
+	}
+
+	// rotated counter clockwise the right pixel ends up on top
+	rotated = ApplyOrientation(img, 8)
+	if rotated.At(0, 0) != blue || rotated.At(0, 1) != red {
+		t.Errorf("unexpected pixels %v %v", rotated.At(0, 0), rotated.At(0, 1))
+	}
+}
+
+func TestAugment(t *testing.T) {
+	// the red channel increases from left to right, so the crops and the mirror images differ
+	img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	for y := 0; y < 48; y++ {
+		for x := 0; x < 64; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 4), uint8(y * 4), 0, 255})
+		}
+	}
+
+	p, err := New(Resize, 32, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tensors, err := Augment(p, img, AugmentationTenCrop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tensors) != NumCrops(AugmentationTenCrop) {
+		t.Fatalf("expected %d crops but got %d", NumCrops(AugmentationTenCrop), len(tensors))
+	}
+
+	central, err := p.Preprocess(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range central.Data {
+		if central.Data[i] != tensors[0].Data[i] {
+			t.Fatalf("first crop differs from the preprocessed image at %d", i)
+		}
+	}
+
+	if tensors[1].At(0, 0, 0) >= tensors[2].At(0, 0, 0) {
+		t.Errorf("expected top left crop to be left of top right crop")
+	}
+	if tensors[1].At(0, 0, 1) >= tensors[3].At(0, 0, 1) {
+		t.Errorf("expected top left crop to be above bottom left crop")
+	}
+	for i := 0; i < 5; i++ {
+		if tensors[i].At(3, 0, 0) != tensors[i+5].At(3, 31, 0) {
+			t.Errorf("expected crop %d to be the mirror image of crop %d", i+5, i)
+		}
+	}
+
+	if _, err := Augment(p, img, "sevenCrop"); err == nil {
+		t.Errorf("expected error for unknown augmentation")
+	}
+}
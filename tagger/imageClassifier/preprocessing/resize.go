@@ -0,0 +1,32 @@
+package preprocessing
+
+import "image"
+
+type resizePreprocessor struct {
+	height, width int
+}
+
+func (p *resizePreprocessor) Size() (int, int) {
+	return p.height, p.width
+}
+
+func (p *resizePreprocessor) Preprocess(img image.Image) (*Tensor, error) {
+	return p.cropAt(p.prepare(img), CropCenter)
+}
+
+func (p *resizePreprocessor) prepare(img image.Image) *Tensor {
+	return FromImage(img)
+}
+
+// cropAt resizes the whole image for the center. For the other positions a crop of the same size
+// as for the inception preprocessing is taken.
+func (p *resizePreprocessor) cropAt(t *Tensor, position CropPosition) (*Tensor, error) {
+	if position != CropCenter {
+		cropped, err := CropAt(t, position, int(float64(t.Height)*centralFraction), int(float64(t.Width)*centralFraction))
+		if err != nil {
+			return nil, err
+		}
+		t = cropped
+	}
+	return ResizeBilinear(t, p.height, p.width), nil
+}
@@ -0,0 +1,69 @@
+package preprocessing
+
+import (
+	"image"
+	"math"
+)
+
+// based on https://github.com/tensorflow/models/blob/master/research/slim/preprocessing/vgg_preprocessing.py
+
+const (
+	R_MEAN = 123.68
+	G_MEAN = 116.78
+	B_MEAN = 103.94
+
+	resizeSideMin = 256
+)
+
+type vggPreprocessor struct {
+	height, width int
+	resizeSide    int
+}
+
+func (p *vggPreprocessor) Size() (int, int) {
+	return p.height, p.width
+}
+
+func (p *vggPreprocessor) Preprocess(img image.Image) (*Tensor, error) {
+	return p.cropAt(p.prepare(img), CropCenter)
+}
+
+// prepare resizes the smaller side of the image, the crops are taken from the resized image.
+func (p *vggPreprocessor) prepare(img image.Image) *Tensor {
+	t := FromImage(img)
+
+	newHeight, newWidth := smallestSizeAtLeast(t.Height, t.Width, p.resizeSide)
+	return ResizeBilinear(t, newHeight, newWidth)
+}
+
+func (p *vggPreprocessor) cropAt(t *Tensor, position CropPosition) (*Tensor, error) {
+	t, err := CropAt(t, position, p.height, p.width)
+	if err != nil {
+		return nil, err
+	}
+
+	SubtractMean(t, []float32{R_MEAN, G_MEAN, B_MEAN})
+	return t, nil
+}
+
+// SubtractMean subtracts the given means from each channel of the tensor.
+func SubtractMean(t *Tensor, means []float32) {
+	for i := range t.Data {
+		t.Data[i] -= means[i%t.Channels]
+	}
+}
+
+// smallestSizeAtLeast computes the new shape with the smallest side equal to smallestSide
+// while preserving the original aspect ratio.
+func smallestSizeAtLeast(height, width, smallestSide int) (newHeight, newWidth int) {
+	var scale float64
+	if height > width {
+		scale = float64(smallestSide) / float64(width)
+	} else {
+		scale = float64(smallestSide) / float64(height)
+	}
+
+	newHeight = int(math.Round(float64(height) * scale))
+	newWidth = int(math.Round(float64(width) * scale))
+	return
+}
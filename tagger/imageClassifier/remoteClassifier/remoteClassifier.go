@@ -0,0 +1,237 @@
+// The package remoteClassifier implements an image classifier which sends the images to a classification
+// server over HTTP. This allows to use models which are served by other frameworks (e.g. PyTorch) for
+// tagging.
+//
+// The images are sent as a POST request with a JSON body to the configured URL:
+//
+//	{
+//	  "k": 5,
+//	  "images": [
+//	    {"id": "pics/bus.jpg", "data": "<base64 encoded image file>"},
+//	    {"id": "pics/cat.jpg", "tensor": {"shape": [224, 224, 3], "values": [...]}}
+//	  ]
+//	}
+//
+// Depending on the input type either the file ("data") or the preprocessed image in height, width,
+// channel order ("tensor") is sent. If k is 0 the server should return the probabilities of all labels,
+// otherwise only the k most probable ones. The server responds with one result per image, the results
+// are matched to the images by their id:
+//
+//	{
+//	  "results": [
+//	    {"id": "pics/bus.jpg", "labels": ["school bus", "minibus"], "probabilities": [0.93, 0.02]},
+//	    {"id": "pics/cat.jpg", "error": "could not decode image"}
+//	  ]
+//	}
+//
+// Optionally a result can contain the WordNet ids of the labels in "synsets" (e.g. ["n04146614", "n03769881"]).
+package remoteClassifier
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"github.com/twatzl/imtag/tagger/image"
+	"github.com/twatzl/imtag/tagger/imageClassifier"
+	"github.com/twatzl/imtag/tagger/imageClassifier/preprocessing"
+	"github.com/twatzl/imtag/tagger/tag"
+	"io/ioutil"
+	"time"
+)
+
+// Types of input which can be sent to the server.
+const (
+	// InputImage sends the image files, the server is responsible for decoding and preprocessing.
+	InputImage = "image"
+	// InputTensor sends the images preprocessed with the configured preprocessing.
+	InputTensor = "tensor"
+)
+
+const defaultBatchSize = 16
+
+type RemoteClassifier interface {
+	imageClassifier.ImageClassifier
+}
+
+type remoteClassifier struct {
+	log          *logrus.Logger
+	config       Config
+	client       *imageClassifier.JSONClient
+	preprocessor preprocessing.Preprocessor
+}
+
+type Config struct {
+	/* the url of the classification endpoint, e.g. http://localhost:5000/classify */
+	URL string
+	/* the timeout for a single request */
+	Timeout time.Duration
+	/* how often a failed request is repeated, see imageClassifier.Retries */
+	Retries *int
+	/* the number of images which are sent in one request */
+	BatchSize int
+	/* whether the image files or preprocessed tensors are sent (default is image) */
+	InputType string
+	/* the preprocessing used for the tensor input type */
+	Preprocessing string
+	InputHeight   int
+	InputWidth    int
+}
+
+type request struct {
+	K      int            `json:"k"`
+	Images []requestImage `json:"images"`
+}
+
+type requestImage struct {
+	Id     string         `json:"id"`
+	Data   []byte         `json:"data,omitempty"`
+	Tensor *requestTensor `json:"tensor,omitempty"`
+}
+
+type requestTensor struct {
+	Shape  []int     `json:"shape"`
+	Values []float32 `json:"values"`
+}
+
+type response struct {
+	Results []result `json:"results"`
+}
+
+type result struct {
+	Id            string    `json:"id"`
+	Labels        []string  `json:"labels"`
+	Probabilities []float32 `json:"probabilities"`
+	Synsets       []string  `json:"synsets"`
+	Error         string    `json:"error"`
+}
+
+func New(log *logrus.Logger, config Config) RemoteClassifier {
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultBatchSize
+	}
+
+	classifier := &remoteClassifier{
+		log:    log,
+		config: config,
+		client: imageClassifier.NewJSONClient(log, config.Timeout, config.Retries),
+	}
+
+	if config.InputType == InputTensor {
+		var err error
+		classifier.preprocessor, err = preprocessing.New(config.Preprocessing, config.InputHeight, config.InputWidth)
+		if err != nil {
+			log.WithError(err).Errorln("could not create preprocessing")
+		}
+	}
+
+	return classifier
+}
+
+func (rc *remoteClassifier) ClassifyImages(images []image.Image) ([][]tag.Tag, error) {
+	return rc.classifyImagesInBatches(images, 0)
+}
+
+func (rc *remoteClassifier) ClassifyImagesTopK(images []image.Image, k int) ([][]tag.Tag, error) {
+	return rc.classifyImagesInBatches(images, k)
+}
+
+// classifyImagesInBatches sends the images in batches of the configured size to the server. Images
+// which can not be read or which the server could not classify get an error and no tags.
+func (rc *remoteClassifier) classifyImagesInBatches(images []image.Image, k int) ([][]tag.Tag, error) {
+	tags := make([][]tag.Tag, len(images))
+	for start := 0; start < len(images); start += rc.config.BatchSize {
+		end := start + rc.config.BatchSize
+		if end > len(images) {
+			end = len(images)
+		}
+
+		req := request{K: k}
+		// positions holds the index of each sent image in the images slice
+		positions := make([]int, 0, end-start)
+		for i := start; i < end; i++ {
+			reqImage, err := rc.makeRequestImage(images[i].GetFilename())
+			if err != nil {
+				rc.log.WithField("file", images[i].GetFilename()).WithError(err).Errorln("error loading image")
+				images[i].SetError(err)
+				continue
+			}
+			req.Images = append(req.Images, reqImage)
+			positions = append(positions, i)
+		}
+
+		if len(req.Images) == 0 {
+			continue
+		}
+
+		resp := &response{}
+		if err := rc.client.Post(rc.config.URL, req, resp); err != nil {
+			return tags, err
+		}
+		if len(resp.Results) != len(req.Images) {
+			return tags, errors.New(fmt.Sprintf("server returned %d results for %d images", len(resp.Results), len(req.Images)))
+		}
+
+		// ids are the filenames, so the same id can occur more than once in a batch
+		idPositions := make(map[string][]int, len(positions))
+		for i, reqImage := range req.Images {
+			idPositions[reqImage.Id] = append(idPositions[reqImage.Id], positions[i])
+		}
+
+		for _, res := range resp.Results {
+			if len(idPositions[res.Id]) == 0 {
+				return tags, errors.New(fmt.Sprintf("server returned a result for unknown image %s", res.Id))
+			}
+			position := idPositions[res.Id][0]
+			idPositions[res.Id] = idPositions[res.Id][1:]
+
+			img := images[position]
+			if res.Error != "" {
+				img.SetError(errors.New(res.Error))
+				continue
+			}
+			if len(res.Labels) != len(res.Probabilities) {
+				img.SetError(errors.New("number of labels and probabilities returned by the server must match"))
+				continue
+			}
+
+			imageTags := make([]tag.Tag, len(res.Labels))
+			for j := range res.Labels {
+				imageTags[j] = imageClassifier.NewTag(res.Labels, res.Synsets, j, res.Probabilities[j])
+			}
+			if k > 0 {
+				imageTags = imageClassifier.TopKTags(imageTags, k)
+			}
+			tags[position] = imageTags
+		}
+	}
+
+	return tags, nil
+}
+
+func (rc *remoteClassifier) makeRequestImage(filename string) (requestImage, error) {
+	if rc.config.InputType != InputTensor {
+		data, err := ioutil.ReadFile(filename)
+		return requestImage{Id: filename, Data: data}, err
+	}
+
+	if rc.preprocessor == nil {
+		return requestImage{}, errors.New("no preprocessing configured")
+	}
+
+	img, err := preprocessing.LoadImage(filename)
+	if err != nil {
+		return requestImage{}, err
+	}
+	tensor, err := rc.preprocessor.Preprocess(img)
+	if err != nil {
+		return requestImage{}, err
+	}
+
+	return requestImage{
+		Id: filename,
+		Tensor: &requestTensor{
+			Shape:  []int{tensor.Height, tensor.Width, tensor.Channels},
+			Values: tensor.Data,
+		},
+	}, nil
+}
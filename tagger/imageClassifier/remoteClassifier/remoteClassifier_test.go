@@ -0,0 +1,176 @@
+package remoteClassifier
+
+import (
+	"encoding/json"
+	"github.com/sirupsen/logrus"
+	"github.com/twatzl/imtag/tagger/image"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+)
+
+func writeImageFiles(t *testing.T, names ...string) (dir string, images []image.Image) {
+	dir, err := ioutil.TempDir("", "remoteClassifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range names {
+		filename := path.Join(dir, name)
+		if err := ioutil.WriteFile(filename, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		images = append(images, image.New(filename))
+	}
+	return dir, images
+}
+
+func TestRemoteClassifier_ClassifyImagesTopK(t *testing.T) {
+	var requests []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		requests = append(requests, req)
+
+		resp := response{}
+		for _, img := range req.Images {
+			if string(img.Data) == "broken.jpg" {
+				resp.Results = append(resp.Results, result{Id: img.Id, Error: "could not decode image"})
+				continue
+			}
+			resp.Results = append(resp.Results, result{
+				Id:            img.Id,
+				Labels:        []string{"cat", "dog", "bus"},
+				Probabilities: []float32{0.2, 0.7, 0.1},
+			})
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	dir, images := writeImageFiles(t, "a.jpg", "broken.jpg", "c.jpg")
+	defer os.RemoveAll(dir)
+	images = append(images, image.New(path.Join(dir, "missing.jpg")))
+
+	classifier := New(logrus.New(), Config{URL: server.URL, BatchSize: 2})
+	tags, err := classifier.ClassifyImagesTopK(images, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(requests) != 2 || len(requests[0].Images) != 2 || len(requests[1].Images) != 1 {
+		t.Errorf("expected batches of 2 and 1 images but got %v", requests)
+	}
+	if requests[0].K != 2 {
+		t.Errorf("expected k to be sent to the server")
+	}
+
+	if len(tags[0]) != 2 || tags[0][0].GetLabel() != "dog" || tags[0][1].GetLabel() != "cat" {
+		t.Errorf("unexpected tags %v", tags[0])
+	}
+	if images[1].GetError() == nil || tags[1] != nil {
+		t.Errorf("expected error for image the server could not classify")
+	}
+	if images[2].GetError() != nil || len(tags[2]) != 2 {
+		t.Errorf("unexpected result for third image: %v %v", tags[2], images[2].GetError())
+	}
+	if images[3].GetError() == nil {
+		t.Errorf("expected error for missing file")
+	}
+}
+
+func TestRemoteClassifier_Retries(t *testing.T) {
+	dir, images := writeImageFiles(t, "a.jpg")
+	defer os.RemoveAll(dir)
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 2 {
+			http.Error(w, "overloaded", http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(response{Results: []result{{Id: images[0].GetFilename(), Labels: []string{"cat"}, Probabilities: []float32{1}}}})
+	}))
+	defer server.Close()
+
+	retries := 1
+	classifier := New(logrus.New(), Config{URL: server.URL, Retries: &retries})
+	tags, err := classifier.ClassifyImages(images)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 || len(tags[0]) != 1 {
+		t.Errorf("expected a successful retry but got %d calls and tags %v", calls, tags)
+	}
+
+	// client errors are not retried
+	calls = 0
+	badRequestServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.Error(w, "bad request", http.StatusBadRequest)
+	}))
+	defer badRequestServer.Close()
+
+	classifier = New(logrus.New(), Config{URL: badRequestServer.URL, Retries: &retries})
+	_, err = classifier.ClassifyImages(images)
+	if err == nil || calls != 1 {
+		t.Errorf("expected a single failed request but got %d calls", calls)
+	}
+}
+
+func TestRemoteClassifier_ReorderedResults(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+
+		// the results are returned in reverse order, each image gets its file content as label
+		resp := response{}
+		for i := len(req.Images) - 1; i >= 0; i-- {
+			resp.Results = append(resp.Results, result{
+				Id:            req.Images[i].Id,
+				Labels:        []string{string(req.Images[i].Data)},
+				Probabilities: []float32{1},
+			})
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	dir, images := writeImageFiles(t, "a.jpg", "b.jpg", "c.jpg")
+	defer os.RemoveAll(dir)
+
+	classifier := New(logrus.New(), Config{URL: server.URL})
+	tags, err := classifier.ClassifyImages(images)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, name := range []string{"a.jpg", "b.jpg", "c.jpg"} {
+		if len(tags[i]) != 1 || tags[i][0].GetLabel() != name {
+			t.Errorf("expected tag %s for image %d but got %v", name, i, tags[i])
+		}
+	}
+}
+
+func TestRemoteClassifier_UnknownResultId(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(response{Results: []result{{Id: "other.jpg", Labels: []string{"cat"}, Probabilities: []float32{1}}}})
+	}))
+	defer server.Close()
+
+	dir, images := writeImageFiles(t, "a.jpg")
+	defer os.RemoveAll(dir)
+
+	classifier := New(logrus.New(), Config{URL: server.URL})
+	if _, err := classifier.ClassifyImages(images); err == nil {
+		t.Errorf("expected error for result of an image which was not sent")
+	}
+}
@@ -0,0 +1,312 @@
+// The package subprocessClassifier implements an image classifier which runs an external executable
+// and communicates with it over stdin and stdout. This allows to write classifiers in any language.
+//
+// The process is started on the first request and kept alive for all further requests. For each batch
+// of images one line of JSON is written to stdin of the process:
+//
+//	{"id": 1, "k": 5, "images": ["pics/bus.jpg", "pics/cat.jpg"]}
+//
+// The process has to answer with one line of JSON on stdout containing a result for each image, in the
+// same order as the images of the request:
+//
+//	{"id": 1, "results": [
+//	  {"labels": ["school bus", "minibus"], "probabilities": [0.93, 0.02]},
+//	  {"error": "could not decode image"}
+//	]}
+//
+// If k is 0 the probabilities of all labels should be returned, otherwise only the k most probable
+// ones. The WordNet ids of the labels can be added to a result in "synsets". If the whole request
+// fails the process can respond with {"id": 1, "error": "..."}. Everything the process writes to
+// stderr is passed through. If the process exits or does not answer in time it is restarted.
+package subprocessClassifier
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"github.com/twatzl/imtag/tagger/image"
+	"github.com/twatzl/imtag/tagger/imageClassifier"
+	"github.com/twatzl/imtag/tagger/tag"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+)
+
+const (
+	defaultTimeout   = 60 * time.Second
+	defaultRetries   = 1
+	defaultBatchSize = 16
+)
+
+type SubprocessClassifier interface {
+	imageClassifier.ImageClassifier
+	Close() error
+}
+
+type subprocessClassifier struct {
+	log    *logrus.Logger
+	config Config
+	// process is nil as long as no process is running
+	process   *process
+	requestId int64
+}
+
+// process holds a running instance of the executable.
+type process struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	// lines receives the lines written to stdout. it is closed when stdout is closed.
+	lines chan []byte
+}
+
+type Config struct {
+	/* the executable which is started */
+	Command string
+	/* the arguments passed to the executable */
+	Args []string
+	/* how long to wait for the response to a request */
+	Timeout time.Duration
+	/* how often a request is repeated with a restarted process, see imageClassifier.Retries (default is 1) */
+	Retries *int
+	/* the number of images which are sent in one request */
+	BatchSize int
+}
+
+type request struct {
+	Id     int64    `json:"id"`
+	K      int      `json:"k"`
+	Images []string `json:"images"`
+}
+
+type response struct {
+	Id      int64    `json:"id"`
+	Results []result `json:"results"`
+	Error   string   `json:"error"`
+}
+
+type result struct {
+	Labels        []string  `json:"labels"`
+	Probabilities []float32 `json:"probabilities"`
+	Synsets       []string  `json:"synsets"`
+	Error         string    `json:"error"`
+}
+
+func New(log *logrus.Logger, config Config) SubprocessClassifier {
+	if config.Timeout <= 0 {
+		config.Timeout = defaultTimeout
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultBatchSize
+	}
+
+	return &subprocessClassifier{
+		log:    log,
+		config: config,
+	}
+}
+
+func (sc *subprocessClassifier) ClassifyImages(images []image.Image) ([][]tag.Tag, error) {
+	return sc.classifyImagesInBatches(images, 0)
+}
+
+func (sc *subprocessClassifier) ClassifyImagesTopK(images []image.Image, k int) ([][]tag.Tag, error) {
+	return sc.classifyImagesInBatches(images, k)
+}
+
+// Close stops the process. It is started again on the next request.
+func (sc *subprocessClassifier) Close() error {
+	if sc.process == nil {
+		return nil
+	}
+
+	p := sc.process
+	sc.process = nil
+
+	// closing stdin tells the process to exit, if it does not exit in time it is killed. stdout is
+	// drained, otherwise a process which writes on exit would block and never exit. Wait must only
+	// be called after stdout was read completely.
+	p.stdin.Close()
+	done := make(chan error, 1)
+	go func() {
+		for range p.lines {
+		}
+		done <- p.cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(sc.config.Timeout):
+		p.cmd.Process.Kill()
+		return <-done
+	}
+}
+
+func (sc *subprocessClassifier) classifyImagesInBatches(images []image.Image, k int) ([][]tag.Tag, error) {
+	tags := make([][]tag.Tag, len(images))
+	for start := 0; start < len(images); start += sc.config.BatchSize {
+		end := start + sc.config.BatchSize
+		if end > len(images) {
+			end = len(images)
+		}
+
+		filenames := make([]string, 0, end-start)
+		for _, img := range images[start:end] {
+			filenames = append(filenames, img.GetFilename())
+		}
+
+		resp, err := sc.send(filenames, k)
+		if err != nil {
+			return tags, err
+		}
+
+		for i, res := range resp.Results {
+			img := images[start+i]
+			if res.Error != "" {
+				img.SetError(errors.New(res.Error))
+				continue
+			}
+			if len(res.Labels) != len(res.Probabilities) {
+				img.SetError(errors.New("number of labels and probabilities returned by the classifier must match"))
+				continue
+			}
+
+			imageTags := make([]tag.Tag, len(res.Labels))
+			for j := range res.Labels {
+				imageTags[j] = imageClassifier.NewTag(res.Labels, res.Synsets, j, res.Probabilities[j])
+			}
+			if k > 0 {
+				imageTags = imageClassifier.TopKTags(imageTags, k)
+			}
+			tags[start+i] = imageTags
+		}
+	}
+
+	return tags, nil
+}
+
+// send writes the request to the process and waits for the response. If the process crashed or did
+// not answer in time it is restarted and the request is repeated.
+func (sc *subprocessClassifier) send(filenames []string, k int) (*response, error) {
+	var lastErr error
+	retries := imageClassifier.Retries(sc.config.Retries, defaultRetries)
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			sc.log.WithField("attempt", attempt).WithError(lastErr).Warnln("restarting classifier process")
+		}
+
+		sc.requestId++
+		resp, err := sc.request(request{Id: sc.requestId, K: k, Images: filenames})
+		if err == nil {
+			return resp, nil
+		}
+		lastErr = err
+
+		// the state of the process is unknown, so it is stopped and started again for the next request
+		sc.kill()
+	}
+
+	sc.log.WithField("command", sc.config.Command).WithError(lastErr).Errorln("classification request failed")
+	return nil, lastErr
+}
+
+func (sc *subprocessClassifier) request(req request) (*response, error) {
+	if sc.process == nil {
+		if err := sc.start(); err != nil {
+			return nil, err
+		}
+	}
+
+	line, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := sc.process.stdin.Write(append(line, '\n')); err != nil {
+		return nil, errors.New(fmt.Sprintf("could not write to classifier process: %s", err.Error()))
+	}
+
+	timeout := time.After(sc.config.Timeout)
+	for {
+		select {
+		case line, ok := <-sc.process.lines:
+			if !ok {
+				return nil, errors.New("classifier process exited")
+			}
+
+			resp := &response{}
+			if err := json.Unmarshal(line, resp); err != nil {
+				return nil, errors.New(fmt.Sprintf("invalid response from classifier process: %s", err.Error()))
+			}
+			if resp.Id != req.Id {
+				// response to an earlier request
+				continue
+			}
+			if resp.Error != "" {
+				return nil, errors.New(resp.Error)
+			}
+			if len(resp.Results) != len(req.Images) {
+				return nil, errors.New(fmt.Sprintf("classifier process returned %d results for %d images", len(resp.Results), len(req.Images)))
+			}
+			return resp, nil
+		case <-timeout:
+			return nil, errors.New(fmt.Sprintf("classifier process did not respond within %s", sc.config.Timeout))
+		}
+	}
+}
+
+func (sc *subprocessClassifier) start() error {
+	cmd := exec.Command(sc.config.Command, sc.config.Args...)
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	sc.log.WithField("command", sc.config.Command).Infoln("starting classifier process")
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	lines := make(chan []byte)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(stdout)
+		// responses with all labels can be long
+		scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+		for scanner.Scan() {
+			line := make([]byte, len(scanner.Bytes()))
+			copy(line, scanner.Bytes())
+			lines <- line
+		}
+	}()
+
+	sc.process = &process{cmd: cmd, stdin: stdin, lines: lines}
+	return nil
+}
+
+// kill stops the process without waiting for it to exit on its own.
+func (sc *subprocessClassifier) kill() {
+	if sc.process == nil {
+		return
+	}
+
+	p := sc.process
+	sc.process = nil
+	p.stdin.Close()
+	p.cmd.Process.Kill()
+
+	// drain stdout so the reading goroutine can exit
+	go func() {
+		for range p.lines {
+		}
+		p.cmd.Wait()
+	}()
+}
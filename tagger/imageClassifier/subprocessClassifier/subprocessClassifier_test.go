@@ -0,0 +1,152 @@
+package subprocessClassifier
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"github.com/twatzl/imtag/tagger/image"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+// TestHelperProcess is not a real test. It is started as classifier process by the other tests.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var req request
+		json.Unmarshal(scanner.Bytes(), &req)
+
+		resp := response{Id: req.Id}
+		for _, img := range req.Images {
+			switch path.Base(img) {
+			case "crash.jpg":
+				// crash only the first time, the marker file is created by the test
+				if _, err := os.Stat(img); err == nil {
+					os.Remove(img)
+					os.Exit(1)
+				}
+			case "slow.jpg":
+				time.Sleep(time.Minute)
+			case "broken.jpg":
+				resp.Results = append(resp.Results, result{Error: "could not decode image"})
+				continue
+			}
+			resp.Results = append(resp.Results, result{
+				Labels:        []string{"cat", "dog", "bus"},
+				Probabilities: []float32{0.2, 0.7, 0.1},
+			})
+		}
+
+		line, _ := json.Marshal(resp)
+		fmt.Println(string(line))
+	}
+
+	// a chatty process writes more than fits into the pipe when it exits
+	if os.Getenv("HELPER_CHATTY") == "1" {
+		for i := 0; i < 100000; i++ {
+			fmt.Println("shutting down the classifier")
+		}
+	}
+	os.Exit(0)
+}
+
+func newHelperClassifier(timeout time.Duration) SubprocessClassifier {
+	os.Setenv("GO_WANT_HELPER_PROCESS", "1")
+	return New(logrus.New(), Config{
+		Command:   os.Args[0],
+		Args:      []string{"-test.run=TestHelperProcess"},
+		Timeout:   timeout,
+		BatchSize: 2,
+	})
+}
+
+func TestSubprocessClassifier_ClassifyImagesTopK(t *testing.T) {
+	classifier := newHelperClassifier(10 * time.Second)
+	defer classifier.Close()
+
+	images := []image.Image{image.New("a.jpg"), image.New("broken.jpg"), image.New("c.jpg")}
+	tags, err := classifier.ClassifyImagesTopK(images, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tags[0]) != 2 || tags[0][0].GetLabel() != "dog" || tags[0][1].GetLabel() != "cat" {
+		t.Errorf("unexpected tags %v", tags[0])
+	}
+	if images[1].GetError() == nil || tags[1] != nil {
+		t.Errorf("expected error for image the classifier could not classify")
+	}
+	if len(tags[2]) != 2 {
+		t.Errorf("unexpected tags %v", tags[2])
+	}
+}
+
+func TestSubprocessClassifier_RestartAfterCrash(t *testing.T) {
+	dir, err := ioutil.TempDir("", "subprocessClassifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	crashFile := path.Join(dir, "crash.jpg")
+	if err := ioutil.WriteFile(crashFile, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	classifier := newHelperClassifier(10 * time.Second)
+	defer classifier.Close()
+
+	tags, err := classifier.ClassifyImages([]image.Image{image.New(crashFile)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags[0]) != 3 {
+		t.Errorf("expected result from restarted process but got %v", tags[0])
+	}
+}
+
+func TestSubprocessClassifier_Timeout(t *testing.T) {
+	classifier := newHelperClassifier(200 * time.Millisecond)
+	defer classifier.Close()
+
+	start := time.Now()
+	_, err := classifier.ClassifyImages([]image.Image{image.New("slow.jpg")})
+	if err == nil {
+		t.Fatal("expected timeout error")
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("request took %s despite timeout", time.Since(start))
+	}
+
+	// the process is restarted for the next request
+	tags, err := classifier.ClassifyImages([]image.Image{image.New("a.jpg")})
+	if err != nil || len(tags[0]) != 3 {
+		t.Errorf("expected result after timeout but got %v %v", tags, err)
+	}
+}
+
+func TestSubprocessClassifier_CloseChattyProcess(t *testing.T) {
+	os.Setenv("HELPER_CHATTY", "1")
+	defer os.Unsetenv("HELPER_CHATTY")
+
+	classifier := newHelperClassifier(30 * time.Second)
+	if _, err := classifier.ClassifyImages([]image.Image{image.New("a.jpg")}); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	if err := classifier.Close(); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("close took %s", time.Since(start))
+	}
+}
@@ -0,0 +1,106 @@
+package tensorflowImageClassifier
+
+import (
+	"errors"
+	"fmt"
+	tf "github.com/tensorflow/tensorflow/tensorflow/go"
+	taggerImage "github.com/twatzl/imtag/tagger/image"
+	"github.com/twatzl/imtag/tagger/imageClassifier/preprocessing"
+	"reflect"
+)
+
+// ExtractFeatures runs the model up to the configured feature layer in batches of the configured
+// size. The feature tensors are flattened, e.g. an output of shape [batch, 1, 1, 4096] gives vectors
+// of 4096 values. No augmentation is applied, since it would mix the features of different crops.
+func (tfc *tensorFlowClassifier) ExtractFeatures(images []taggerImage.Image) ([][]float32, error) {
+	if tfc.config.FeatureTag == "" {
+		return nil, errors.New("no feature layer configured for the classifier, set featureTag in its definition")
+	}
+	// loading the model fails if the configured feature layer does not exist
+	if err := tfc.verifyModelLoaded(); err != nil {
+		return nil, err
+	}
+
+	batchSize := tfc.config.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+
+	features := make([][]float32, len(images))
+	for start := 0; start < len(images); start += batchSize {
+		end := start + batchSize
+		if end > len(images) {
+			end = len(images)
+		}
+
+		// positions holds the index of each loaded image in the images slice
+		positions := make([]int, 0, end-start)
+		tensors := make([]*preprocessing.Tensor, 0, end-start)
+		for i := start; i < end; i++ {
+			tensor, err := tfc.loadTensorFromImage(images[i].GetFilename())
+			if err != nil {
+				images[i].SetError(err)
+				continue
+			}
+			positions = append(positions, i)
+			tensors = append(tensors, tensor)
+		}
+
+		if len(tensors) == 0 {
+			continue
+		}
+
+		inputTensor, err := tfc.makeBatchTensor(tensors)
+		if err != nil {
+			return features, err
+		}
+
+		results, err := tfc.session.Run(
+			map[tf.Output]*tf.Tensor{tfc.input: inputTensor},
+			[]tf.Output{tfc.features},
+			nil)
+		if err != nil {
+			tfc.log.WithError(err).Errorln("error during run of feature layer")
+			return features, err
+		}
+
+		vectors, err := splitBatch(results[0], len(tensors))
+		if err != nil {
+			return features, errors.New(fmt.Sprintf("feature layer %s: %s", tfc.config.FeatureTag, err.Error()))
+		}
+		for i, vector := range vectors {
+			features[positions[i]] = vector
+		}
+	}
+	return features, nil
+}
+
+// splitBatch flattens a float tensor of any rank whose first dimension is the batch into one
+// vector per element of the batch.
+func splitBatch(t *tf.Tensor, batchSize int) ([][]float32, error) {
+	if t.DataType() != tf.Float {
+		return nil, errors.New("output is not a float tensor")
+	}
+	if shape := t.Shape(); len(shape) == 0 || shape[0] != int64(batchSize) {
+		return nil, errors.New(fmt.Sprintf("output has shape %v, expected the batch as first dimension", shape))
+	}
+
+	// the value is a nested slice with one level per dimension, e.g. [][][][]float32 for rank 4
+	batch := reflect.ValueOf(t.Value())
+	vectors := make([][]float32, batchSize)
+	for i := range vectors {
+		vectors[i] = flatten(batch.Index(i), nil)
+	}
+	return vectors, nil
+}
+
+// flatten appends the float32 values of a nested slice to values in row-major order.
+func flatten(v reflect.Value, values []float32) []float32 {
+	if v.Kind() != reflect.Slice {
+		return append(values, float32(v.Float()))
+	}
+	for i := 0; i < v.Len(); i++ {
+		values = flatten(v.Index(i), values)
+	}
+	return values
+}
@@ -0,0 +1,63 @@
+package tensorflowImageClassifier
+
+import (
+	tf "github.com/tensorflow/tensorflow/tensorflow/go"
+	taggerImage "github.com/twatzl/imtag/tagger/image"
+	"strings"
+	"testing"
+)
+
+func TestSplitBatch(t *testing.T) {
+	// a pooling layer like vgg_19/fc7 with shape [batch, 1, 1, 4096]
+	values := make([][][][]float32, 2)
+	for i := range values {
+		vector := make([]float32, 4096)
+		for j := range vector {
+			vector[j] = float32(i*4096 + j)
+		}
+		values[i] = [][][]float32{{vector}}
+	}
+	tensor, err := tf.NewTensor(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vectors, err := splitBatch(tensor, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vectors) != 2 || len(vectors[0]) != 4096 || len(vectors[1]) != 4096 {
+		t.Fatalf("expected 2 vectors of 4096 values but got %d", len(vectors))
+	}
+	if vectors[0][1] != 1 || vectors[1][0] != 4096 || vectors[1][4095] != 8191 {
+		t.Errorf("unexpected values %v %v %v", vectors[0][1], vectors[1][0], vectors[1][4095])
+	}
+}
+
+func TestSplitBatch_BatchMismatch(t *testing.T) {
+	tensor, err := tf.NewTensor([][]float32{{1, 2}, {3, 4}, {5, 6}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := splitBatch(tensor, 2); err == nil {
+		t.Errorf("expected error for output with batch of 3 instead of 2")
+	}
+}
+
+func TestSplitBatch_NotFloat(t *testing.T) {
+	tensor, err := tf.NewTensor([][]int32{{1, 2}, {3, 4}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := splitBatch(tensor, 2); err == nil {
+		t.Errorf("expected error for int32 output")
+	}
+}
+
+func TestTensorFlowClassifier_ExtractFeaturesWithoutFeatureTag(t *testing.T) {
+	tfc := &tensorFlowClassifier{config: Config{}}
+	_, err := tfc.ExtractFeatures([]taggerImage.Image{taggerImage.New("a.jpg")})
+	if err == nil || !strings.Contains(err.Error(), "featureTag") {
+		t.Errorf("expected error about missing featureTag but got %v", err)
+	}
+}
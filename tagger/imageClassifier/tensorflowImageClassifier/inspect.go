@@ -0,0 +1,159 @@
+package tensorflowImageClassifier
+
+import (
+	tf "github.com/tensorflow/tensorflow/tensorflow/go"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// TensorDescription describes an input or output of a model. Dimensions which are not known are -1,
+// Shape is nil if not even the number of dimensions is known.
+type TensorDescription struct {
+	// Name is the name of the operation in the graph or the key in a signature
+	Name string
+	// Tensor is the name of the tensor in the graph a signature key refers to
+	Tensor string
+	// Operation is the type of the operation producing the tensor (e.g. Placeholder or Softmax)
+	Operation string
+	Shape     []int64
+	// Probabilities tells whether the output is likely normalized by a softmax
+	Probabilities bool
+}
+
+// ModelDescription lists the candidates for the input and output tensors of a model.
+type ModelDescription struct {
+	Inputs  []TensorDescription
+	Outputs []TensorDescription
+	// Signatures are the signatures of a saved model with their inputs and outputs
+	Signatures map[string]SignatureDescription
+}
+
+// SignatureDescription describes the inputs and outputs of a signature of a saved model. The names
+// of the tensors are the keys of the signature.
+type SignatureDescription struct {
+	Inputs  []TensorDescription
+	Outputs []TensorDescription
+}
+
+// operations which have no consumers but are never the output of a classifier
+var ignoredSinkOperations = map[string]bool{
+	"NoOp": true, "Const": true, "Assert": true, "SaveV2": true, "RestoreV2": true,
+	"VariableV2": true, "VarHandleOp": true, "AssignVariableOp": true, "MergeV2Checkpoints": true,
+}
+
+// InspectFrozenModel loads a frozen graph and returns its placeholders and the operations which are
+// likely outputs of the classifier.
+func InspectFrozenModel(modelFile string) (*ModelDescription, error) {
+	modelData, err := ioutil.ReadFile(modelFile)
+	if err != nil {
+		return nil, err
+	}
+
+	graph := tf.NewGraph()
+	if err := graph.Import(modelData, ""); err != nil {
+		return nil, err
+	}
+
+	return inspectGraph(graph), nil
+}
+
+// InspectSavedModel loads a saved model with the given tags and returns the candidates of its graph
+// together with its signatures.
+func InspectSavedModel(modelFolder string, tags []string) (*ModelDescription, error) {
+	if len(tags) == 0 {
+		tags = []string{defaultSavedModelTag}
+	}
+
+	model, err := tf.LoadSavedModel(modelFolder, tags, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer model.Session.Close()
+
+	description := inspectGraph(model.Graph)
+	description.Signatures = map[string]SignatureDescription{}
+	for name, signature := range model.Signatures {
+		description.Signatures[name] = SignatureDescription{
+			Inputs:  describeSignatureTensors(signature.Inputs),
+			Outputs: describeSignatureTensors(signature.Outputs),
+		}
+	}
+	return description, nil
+}
+
+// inspectGraph collects the placeholders of the graph as inputs. Softmax operations and float
+// outputs without consumers are collected as outputs, since the last layer of a classifier is
+// usually not used by any other operation.
+func inspectGraph(graph *tf.Graph) *ModelDescription {
+	description := &ModelDescription{}
+
+	for _, operation := range graph.Operations() {
+		if operation.NumOutputs() == 0 {
+			continue
+		}
+		output := operation.Output(0)
+
+		if operation.Type() == "Placeholder" {
+			description.Inputs = append(description.Inputs, describeOutput(&operation, output))
+			continue
+		}
+
+		if output.DataType() != tf.Float || ignoredSinkOperations[operation.Type()] {
+			continue
+		}
+		if operation.Type() == "Softmax" || len(output.Consumers()) == 0 {
+			description.Outputs = append(description.Outputs, describeOutput(&operation, output))
+		}
+	}
+
+	sort.Slice(description.Outputs, func(i, j int) bool {
+		return description.Outputs[i].Name < description.Outputs[j].Name
+	})
+	return description
+}
+
+func describeOutput(operation *tf.Operation, output tf.Output) TensorDescription {
+	return TensorDescription{
+		Name:          operation.Name(),
+		Operation:     operation.Type(),
+		Shape:         shapeToSlice(output.Shape()),
+		Probabilities: isProbabilityOutput(operation.Name(), operation.Type()),
+	}
+}
+
+func describeSignatureTensors(tensors map[string]tf.TensorInfo) []TensorDescription {
+	descriptions := []TensorDescription{}
+	for key, info := range tensors {
+		descriptions = append(descriptions, TensorDescription{
+			Name:          key,
+			Tensor:        info.Name,
+			Shape:         shapeToSlice(info.Shape),
+			Probabilities: isProbabilityOutput(key, ""),
+		})
+	}
+
+	sort.Slice(descriptions, func(i, j int) bool {
+		return descriptions[i].Name < descriptions[j].Name
+	})
+	return descriptions
+}
+
+// isProbabilityOutput guesses from the name and type of an operation whether its output is already
+// normalized. Models usually reshape the output of the softmax, so the name is checked as well.
+func isProbabilityOutput(name string, operationType string) bool {
+	name = strings.ToLower(name)
+	return operationType == "Softmax" || strings.Contains(name, "softmax") ||
+		strings.Contains(name, "predictions") || strings.Contains(name, "probabilities")
+}
+
+func shapeToSlice(shape tf.Shape) []int64 {
+	if shape.NumDimensions() < 0 {
+		return nil
+	}
+	dims := make([]int64, shape.NumDimensions())
+	for i := range dims {
+		dims[i] = shape.Size(i)
+	}
+	return dims
+}
@@ -2,50 +2,103 @@ package tensorflowImageClassifier
 
 import (
 	"bufio"
+	"bytes"
 	"errors"
-	tg "github.com/galeone/tfgo"
+	"fmt"
 	"github.com/sirupsen/logrus"
 	tf "github.com/tensorflow/tensorflow/tensorflow/go"
 	"github.com/tensorflow/tensorflow/tensorflow/go/op"
 	taggerImage "github.com/twatzl/imtag/tagger/image"
 	"github.com/twatzl/imtag/tagger/imageClassifier"
-	"github.com/twatzl/imtag/tagger/imageClassifier/tensorflowImageClassifier/vggPreprocessing"
+	"github.com/twatzl/imtag/tagger/imageClassifier/preprocessing"
 	"github.com/twatzl/imtag/tagger/tag"
-	"image"
-	"image/draw"
-	_ "image/jpeg"
 	"io/ioutil"
 	"os"
+	"strconv"
+	"strings"
+)
+
+// defaultBatchSize is used if no batch size is configured.
+const defaultBatchSize = 16
+
+// defaults for loading saved models
+const (
+	defaultSavedModelTag = "serve"
+	defaultSignature     = "serving_default"
 )
 
 type TensorFlowClassifier interface {
-	LoadSavedModel(modelFolder string, graphTag string)
+	LoadSavedModel(modelFolder string) (err error)
 	LoadFrozenModel(modelFile string) (err error)
+	Close() error
 	GetTopKPredictionsForImage(imageFilename string, k int) (values [][]float32, indices [][]int32, err error)
+	GetTopKPredictionsForImages(imageFilenames []string, k int) (values [][]float32, indices [][]int32, err error)
 	GetLabelsForPredictions(batchValues [][]float32, batchIndices [][]int32) []map[string]float32
 	imageClassifier.ImageClassifier
+	imageClassifier.FeatureExtractor
 }
 
 type tensorFlowClassifier struct {
 	dataPathForResource func(string) string
 	log                 *logrus.Logger
 	labels              []string
-	savedModel          *tg.Model
-	frozenModel         *tf.Graph
+	synsetIds           []string
+	savedModel          *tf.SavedModel
+	// graph is the graph of the loaded model, either a frozen graph or the graph of a saved model
+	graph               *tf.Graph
 	config              Config
+	preprocessor        preprocessing.Preprocessor
+	// the session is kept open as long as the model is loaded
+	session *tf.Session
+	input   tf.Output
+	output  tf.Output
+	// probabilities is the output of the model normalized with a softmax if the model returns logits
+	probabilities tf.Output
+	// features is the feature layer of the model, its Op is nil if none is configured
+	features tf.Output
+	// the top k operation is added to the graph of the model so it runs in the same session
+	topKInput   tf.Output
+	topKValues  tf.Output
+	topKIndices tf.Output
 }
 
 type Config struct {
-	/* in newer tensorflow file format the graph has to have a tag */
-	GraphTag string // = "serve"
-	/* the name with which the input node of a graph is labelled */
+	/* in newer tensorflow file format the graph has to have a tag set (default is serve) */
+	GraphTags []string
+	/* the signature of a saved model which defines the input and output tensors (default is serving_default) */
+	Signature string
+	/* the name with which the input node of a graph is labelled. for saved models this can also be the
+	   key of the input in the signature. if empty the only input of the signature is used */
 	InputTag string //= "input"
-	/* the name with which the output node of a graph is labelled */
+	/* the name with which the output node of a graph is labelled. for saved models this can also be the
+	   key of the output in the signature. if empty the only output of the signature is used */
 	OutputTag string // = "resnet_v2_152/predictions/Reshape_1"
+	/* the name of the feature layer (e.g. vgg_19/fc7/Relu), see ExtractFeatures. for saved models this can
+	   also be the key of an output in the signature. optional */
+	FeatureTag string
 	/* the name of the file which contains the imagenet label mappings */
 	LabelFile string // = "imagenet_comp_graph_label_strings.txt"
+	/* the line of the label file which belongs to the first output of the model. 1 skips a "dummy" line,
+	   -1 adds a background label for models with a background class which is not in the label file */
+	LabelOffset int
+	/* the file with the WordNet ids of the labels in the same order as the label file (optional) */
+	WnidLabelFile string
 	/* the number of labels (or classes) this needs to be configurable since some models use 1000 and some 1001 */
 	NumLabels int
+	/* the number of images which are classified in one run of the model */
+	BatchSize int
+	/* the name of the preprocessing which was used during training of the model (e.g. vgg or inception) */
+	Preprocessing string
+	/* the size of the images the model expects as input */
+	InputHeight int
+	InputWidth  int
+	/* whether the model outputs probabilities or logits (see the Output constants in imageClassifier) */
+	OutputType string
+	/* the temperature with which the output is normalized, see the calibrate command (0 means 1) */
+	Temperature float32
+	/* the test-time augmentation, the probabilities of the crops are averaged (see the Augmentation
+	   constants in preprocessing) */
+	Augmentation string
 }
 
 func New(dataPathMapper func(string) string, log *logrus.Logger, config Config) TensorFlowClassifier {
@@ -60,66 +113,135 @@ func New(dataPathMapper func(string) string, log *logrus.Logger, config Config)
 		log.WithError(err).Errorln("could not load labels")
 	}
 
+	if config.WnidLabelFile != "" {
+		classifier.synsetIds, err = imageClassifier.LoadSynsetIds(dataPathMapper(config.WnidLabelFile))
+		if err != nil {
+			log.WithError(err).Errorln("could not load synset ids of labels")
+		}
+	}
+
+	// the labels and synset ids are aligned with the outputs of the model
+	classifier.synsetIds = imageClassifier.AlignSynsetIds(classifier.synsetIds, len(classifier.labels), config.LabelOffset)
+	classifier.labels = imageClassifier.ApplyLabelOffset(classifier.labels, config.LabelOffset, imageClassifier.BackgroundLabel)
+
+	classifier.preprocessor, err = preprocessing.New(config.Preprocessing, config.InputHeight, config.InputWidth)
+	if err != nil {
+		log.WithError(err).Errorln("could not create preprocessing")
+	}
+
 	return classifier
 }
 
 func (tfc *tensorFlowClassifier) ClassifyImages(images []taggerImage.Image) ([][]tag.Tag, error) {
-	tags := [][]tag.Tag{}
-	for _, img := range images {
-
-		// TODO: tensorflow supports batch processing. increase performance by using that
-		values, err := tfc.GetPredictionsForImage(img.GetFilename())
-		if err != nil {
-			return tags, err
-		}
+	return tfc.classifyImagesInBatches(images, 0)
+}
 
-		seq := make([]int32, tfc.config.NumLabels)
-		for i := range seq {
-			seq[i] = int32(i)
-		}
+func (tfc *tensorFlowClassifier) ClassifyImagesTopK(images []taggerImage.Image, k int) ([][]tag.Tag, error) {
+	return tfc.classifyImagesInBatches(images, k)
+}
 
-		labeledResults := tfc.GetLabelsForPredictions(values, [][]int32{seq})[0]
+// classifyImagesInBatches splits the images into batches of the configured size and runs the model
+// once per batch. If k is 0 all predictions are returned, otherwise only the top k. Images which can
+// not be loaded get an error and no tags, the rest of the batch is still classified. With an
+// augmentation all crops of an image are in the same batch and their predictions are averaged.
+func (tfc *tensorFlowClassifier) classifyImagesInBatches(images []taggerImage.Image, k int) ([][]tag.Tag, error) {
+	batchSize := tfc.config.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
 
-		tagsForImage := []tag.Tag{}
-		for key, val := range labeledResults {
-			tagsForImage = append(tagsForImage, tag.New(key, val))
+	tags := make([][]tag.Tag, len(images))
+	for start := 0; start < len(images); start += batchSize {
+		end := start + batchSize
+		if end > len(images) {
+			end = len(images)
 		}
 
-		tags = append(tags, tagsForImage)
-	}
-	return tags, nil
-}
+		// positions holds the index of each loaded image in the images slice, crops the number of
+		// tensors of each loaded image
+		positions := make([]int, 0, end-start)
+		crops := make([]int, 0, end-start)
+		tensors := make([]*preprocessing.Tensor, 0, end-start)
+		for i := start; i < end; i++ {
+			imageTensors, err := tfc.loadCropsFromImage(images[i].GetFilename(), tfc.config.Augmentation)
+			if err != nil {
+				images[i].SetError(err)
+				continue
+			}
+			positions = append(positions, i)
+			crops = append(crops, len(imageTensors))
+			tensors = append(tensors, imageTensors...)
+		}
 
-func (tfc *tensorFlowClassifier) ClassifyImagesTopK(images []taggerImage.Image, k int) ([][]tag.Tag, error) {
-	tags := [][]tag.Tag{}
-	for _, img := range images {
+		if len(tensors) == 0 {
+			continue
+		}
 
-		// TODO: tensorflow supports batch processing. increase performance by using that
-		values, indices, err := tfc.GetTopKPredictionsForImage(img.GetFilename(), k)
+		inputTensor, err := tfc.makeBatchTensor(tensors)
 		if err != nil {
 			return tags, err
 		}
 
-		labeledResults := tfc.GetLabelsForPredictions(values, indices)[0]
+		// the top k of averaged predictions can not be taken in the graph
+		augmented := len(tensors) > len(positions)
+		var values [][]float32
+		var indices [][]int32
+		if k == 0 || augmented {
+			_, values, err = tfc.runClassificationModel(inputTensor)
+			if err == nil && augmented {
+				values = imageClassifier.AverageCrops(values, crops)
+			}
+			indices = allIndices(len(values), tfc.config.NumLabels)
+		} else {
+			values, indices, err = tfc.topKPredictionsForTensor(inputTensor, k)
+		}
+		if err != nil {
+			return tags, err
+		}
 
-		tagsForImage := []tag.Tag{}
-		for key, val := range labeledResults {
-			tagsForImage = append(tagsForImage, tag.New(key, val))
+		if len(values) != len(indices) {
+			return tags, errors.New("batch size for values and indices must match")
+		}
+		for i := range values {
+			imageTags := tfc.tagsForPredictions(values[i], indices[i])
+			if augmented && k > 0 {
+				imageTags = imageClassifier.TopKTags(imageTags, k)
+			}
+			tags[positions[i]] = imageTags
 		}
+	}
+	return tags, nil
+}
 
-		tags = append(tags, tagsForImage)
+// allIndices returns the indices of all labels for each element of the batch.
+func allIndices(batchSize int, numLabels int) [][]int32 {
+	seq := make([]int32, numLabels)
+	for i := range seq {
+		seq[i] = int32(i)
+	}
 
+	indices := make([][]int32, batchSize)
+	for i := range indices {
+		indices[i] = seq
 	}
-	return tags, nil
+	return indices
+}
+
+// tagsForPredictions creates the tags for the predictions of one image. Indices without a label are skipped.
+func (tfc *tensorFlowClassifier) tagsForPredictions(values []float32, indices []int32) []tag.Tag {
+	tags := make([]tag.Tag, 0, len(values))
+	for j, value := range values {
+		if j >= len(indices) || int(indices[j]) >= len(tfc.labels) {
+			continue
+		}
+		tags = append(tags, imageClassifier.NewTag(tfc.labels, tfc.synsetIds, int(indices[j]), value))
+	}
+	return tags
 }
 
 // GetLabelsForPredictions returns a map of labels to values for each element in a batch. Usually this function is called
-// after the top k predictions have been taking from the classification.
+// after the top k predictions have been taking from the classification. Indices without a label are skipped.
 func (tfc *tensorFlowClassifier) GetLabelsForPredictions(batchValues [][]float32, batchIndices [][]int32) []map[string]float32 {
-	/*for i := 0; i < len(values[0]); i++ {
-		fmt.Printf("%f \t %s\n", values[0][i], tfc.labels[indices[0][i]])
-	}*/
-
 	if len(batchValues) != len(batchIndices) {
 		tfc.log.Errorln("batch size for values and indices must match")
 		return nil
@@ -140,6 +262,9 @@ func (tfc *tensorFlowClassifier) GetLabelsForPredictions(batchValues [][]float32
 		mapping := make(map[string]float32)
 
 		for j := 0; j < len(values); j++ {
+			if int(indices[j]) >= len(tfc.labels) {
+				continue
+			}
 			label := tfc.labels[indices[j]]
 			value := values[j]
 			mapping[label] = value
@@ -152,7 +277,13 @@ func (tfc *tensorFlowClassifier) GetLabelsForPredictions(batchValues [][]float32
 }
 
 func (tfc *tensorFlowClassifier) GetPredictionsForImage(imageFilename string) (values [][]float32, err error) {
-	inputTensor, err := tfc.loadTensorFromImage(imageFilename)
+	return tfc.GetPredictionsForImages([]string{imageFilename})
+}
+
+// GetPredictionsForImages runs the model once for the whole batch of images and returns the
+// predictions for each of the images.
+func (tfc *tensorFlowClassifier) GetPredictionsForImages(imageFilenames []string) (values [][]float32, err error) {
+	inputTensor, err := tfc.loadBatchTensorFromImages(imageFilenames)
 	if err != nil {
 		tfc.log.Errorf("Error creating inputTensor tensor: %s\n", err.Error())
 		return
@@ -167,38 +298,134 @@ func (tfc *tensorFlowClassifier) GetPredictionsForImage(imageFilename string) (v
 }
 
 func (tfc *tensorFlowClassifier) GetTopKPredictionsForImage(imageFilename string, k int) (values [][]float32, indices [][]int32, err error) {
-	inputTensor, err := tfc.loadTensorFromImage(imageFilename)
+	return tfc.GetTopKPredictionsForImages([]string{imageFilename}, k)
+}
+
+// GetTopKPredictionsForImages runs the model once for the whole batch of images and returns the top k
+// predictions for each of the images. For frozen models the top k are computed in the same session run.
+func (tfc *tensorFlowClassifier) GetTopKPredictionsForImages(imageFilenames []string, k int) (values [][]float32, indices [][]int32, err error) {
+	inputTensor, err := tfc.loadBatchTensorFromImages(imageFilenames)
 	if err != nil {
 		tfc.log.Errorf("Error creating inputTensor tensor: %s\n", err.Error())
 		return
 	}
-	results, predictions, err := tfc.runClassificationModel(inputTensor)
+
+	return tfc.topKPredictionsForTensor(inputTensor, k)
+}
+
+// topKPredictionsForTensor runs the model for a batch tensor and returns the top k predictions.
+func (tfc *tensorFlowClassifier) topKPredictionsForTensor(inputTensor *tf.Tensor, k int) (values [][]float32, indices [][]int32, err error) {
+	return tfc.runTopKClassificationModel(inputTensor, k)
+}
+
+// LoadSavedModel loads a model in the SavedModel format using the configured tag set. The input and
+// output tensors are resolved from the configured signature.
+func (tfc *tensorFlowClassifier) LoadSavedModel(modelFolder string) (err error) {
+	tfc.Close()
+	modelFolder = tfc.dataPathForResource(modelFolder)
+	tfc.log.WithField("folder", modelFolder).Infoln("loading saved model")
+
+	tags := tfc.config.GraphTags
+	if len(tags) == 0 {
+		tags = []string{defaultSavedModelTag}
+	}
+
+	model, err := tf.LoadSavedModel(modelFolder, tags, nil)
 	if err != nil {
-		// todo
-		tfc.log.WithError(err).Errorln("error during run of classifier model")
-		return
+		tfc.log.WithField("tags", tags).WithError(err).Errorln("could not load saved model")
+		return err
+	}
+
+	signatureName := tfc.config.Signature
+	if signatureName == "" {
+		signatureName = defaultSignature
+	}
+	signature, ok := model.Signatures[signatureName]
+	if !ok && tfc.config.Signature != "" {
+		model.Session.Close()
+		err = errors.New(fmt.Sprintf("signature %s not found in saved model", signatureName))
+		tfc.log.WithError(err).Errorln("error loading saved model")
+		return err
+	}
+
+	// without a signature the tags have to be the names of the operations
+	inputName, outputName, featureName := tfc.config.InputTag, tfc.config.OutputTag, tfc.config.FeatureTag
+	if ok {
+		inputName, err = resolveSignatureTensor(signature.Inputs, inputName)
+		if err == nil {
+			outputName, err = resolveSignatureTensor(signature.Outputs, outputName)
+		}
+		if err == nil && featureName != "" {
+			featureName, err = resolveSignatureTensor(signature.Outputs, featureName)
+		}
+		if err != nil {
+			model.Session.Close()
+			tfc.log.WithField("signature", signatureName).WithError(err).Errorln("error loading saved model")
+			return err
+		}
 	}
 
-	tfc.log.Debugf("Predictions %v \n", predictions[0])
-	valuesTensor, indicesTensor, err := topKPredictions(results[0], k)
+	err = tfc.initModel(model.Graph, model.Session, inputName, outputName, featureName)
 	if err != nil {
-		tfc.log.WithError(err).Errorln("error retrieving top k predictions")
-		return
+		model.Session.Close()
+		return err
 	}
-	values = valuesTensor.Value().([][]float32)
-	indices = indicesTensor.Value().([][]int32)
-	return values, indices, err
+
+	tfc.savedModel = model
+	return nil
 }
 
-func (tfc *tensorFlowClassifier) LoadSavedModel(modelFolder string, graphTag string) {
-	modelFolder = tfc.dataPathForResource(modelFolder)
-	tfc.log.WithField("folder", modelFolder).Infoln("loading saved model")
-	tfc.frozenModel = nil
-	tfc.savedModel = tg.LoadModel(modelFolder, []string{graphTag}, nil)
+// resolveSignatureTensor returns the name of the tensor for the given key of a signature. If the key
+// is empty and the signature has only one tensor, that tensor is used. If the key is not part of the
+// signature it is returned unchanged, so tensor names can also be configured directly.
+func resolveSignatureTensor(tensors map[string]tf.TensorInfo, key string) (string, error) {
+	if key == "" {
+		if len(tensors) != 1 {
+			keys := []string{}
+			for k := range tensors {
+				keys = append(keys, k)
+			}
+			return "", errors.New(fmt.Sprintf("signature has multiple tensors %v, one of them has to be configured", keys))
+		}
+		for _, info := range tensors {
+			return info.Name, nil
+		}
+	}
+
+	if info, ok := tensors[key]; ok {
+		return info.Name, nil
+	}
+	return key, nil
+}
+
+// lookupOutput returns the output of the graph with the given name. The name is either the name of an
+// operation (which refers to its first output) or has the form operation:index.
+func lookupOutput(graph *tf.Graph, name string) (tf.Output, error) {
+	opName, index := name, 0
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		parsed, err := strconv.Atoi(name[i+1:])
+		if err == nil {
+			opName, index = name[:i], parsed
+		}
+	}
+
+	if opName == "" {
+		return tf.Output{}, errors.New("no operation configured, run inspectModel to list the inputs and outputs of the model")
+	}
+
+	operation := graph.Operation(opName)
+	if operation == nil {
+		return tf.Output{}, errors.New(fmt.Sprintf(
+			"operation %s not found in model, run inspectModel to list the inputs and outputs of the model", opName))
+	}
+	if index >= operation.NumOutputs() {
+		return tf.Output{}, errors.New(fmt.Sprintf("operation %s has no output %d", opName, index))
+	}
+	return operation.Output(index), nil
 }
 
 func (tfc *tensorFlowClassifier) LoadFrozenModel(modelFile string) (err error) {
-	tfc.savedModel = nil
+	tfc.Close()
 	filepath := tfc.dataPathForResource(modelFile)
 	tfc.log.WithField("file", filepath).Infoln("loading frozen model")
 	f, err := os.Open(filepath)
@@ -221,56 +448,193 @@ func (tfc *tensorFlowClassifier) LoadFrozenModel(modelFile string) (err error) {
 		return err
 	}
 
-	tfc.frozenModel = graph
+	session, err := tf.NewSession(graph, nil)
+	if err != nil {
+		tfc.log.WithError(err).Errorln("could not create session for frozen model")
+		return err
+	}
+
+	err = tfc.initModel(graph, session, tfc.config.InputTag, tfc.config.OutputTag, tfc.config.FeatureTag)
+	if err != nil {
+		session.Close()
+		return err
+	}
+	return nil
+}
+
+// initModel looks up the input, output and the optional feature layer of the model and adds the top k
+// operation to its graph.
+func (tfc *tensorFlowClassifier) initModel(graph *tf.Graph, session *tf.Session, inputName, outputName, featureName string) (err error) {
+	logger := tfc.log.WithField("input", inputName).WithField("output", outputName)
+
+	tfc.input, err = lookupOutput(graph, inputName)
+	if err != nil {
+		logger.WithError(err).Errorln("error loading model")
+		return err
+	}
+	tfc.output, err = lookupOutput(graph, outputName)
+	if err != nil {
+		logger.WithError(err).Errorln("error loading model")
+		return err
+	}
+	tfc.features = tf.Output{}
+	if featureName != "" {
+		tfc.features, err = lookupOutput(graph, featureName)
+		if err != nil {
+			logger.WithField("features", featureName).WithError(err).Errorln("error loading model")
+			return err
+		}
+	}
+
+	err = tfc.verifyLabels()
+	if err != nil {
+		logger.WithError(err).Errorln("labels do not match the model")
+		return err
+	}
+
+	// add the softmax and top k operations to the graph of the model, the session picks up the new operations
+	tfc.probabilities = tfc.output
+	if imageClassifier.NeedsSoftmax(tfc.config.OutputType, tfc.config.Temperature) {
+		s := op.NewScopeWithGraph(graph).SubScope("imtag_softmax")
+		logits := tfc.output
+		if tfc.config.OutputType != imageClassifier.OutputLogits {
+			logits = op.Log(s, tfc.output)
+		}
+		temperature := tfc.config.Temperature
+		if temperature == 0 {
+			temperature = 1
+		}
+		tfc.probabilities = op.Softmax(s, op.Div(s, logits, op.Const(s, temperature)))
+		if err = s.Err(); err != nil {
+			logger.WithError(err).Errorln("could not add softmax operation to model")
+			return err
+		}
+	}
+
+	s := op.NewScopeWithGraph(graph).SubScope("imtag_topk")
+	tfc.topKInput = op.Placeholder(s, tf.Int32)
+	tfc.topKValues, tfc.topKIndices = op.TopKV2(s, tfc.probabilities, tfc.topKInput)
+	if err = s.Err(); err != nil {
+		logger.WithError(err).Errorln("could not add top k operation to model")
+		return err
+	}
+
+	tfc.graph = graph
+	tfc.session = session
+	return nil
+}
+
+// verifyLabels checks that the number of labels matches the configured number of classes and the
+// shape of the output tensor, if it is known.
+func (tfc *tensorFlowClassifier) verifyLabels() error {
+	shape := tfc.output.Shape()
+	if dims := shape.NumDimensions(); dims > 0 {
+		if size := shape.Size(dims - 1); size > 0 {
+			if err := imageClassifier.VerifyOutputSize(int(size), tfc.config.NumLabels); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := imageClassifier.VerifyLabelCount(tfc.labels, tfc.config.NumLabels); err != nil {
+		return err
+	}
+	if tfc.config.WnidLabelFile != "" {
+		return imageClassifier.VerifySynsetIdCount(tfc.synsetIds, tfc.labels)
+	}
 	return nil
 }
 
+// Close closes the session of a loaded model.
+func (tfc *tensorFlowClassifier) Close() error {
+	tfc.graph = nil
+	tfc.savedModel = nil
+	if tfc.session == nil {
+		return nil
+	}
+
+	err := tfc.session.Close()
+	tfc.session = nil
+	return err
+}
+
 // runClassificationModel will run a previously loaded model on a given input tensor
-// and returns the raw results as well as the predictions from the classification.
+// and returns the raw results as well as the predictions from the classification. The
+// predictions are probabilities, logits are normalized in the graph.
 func (tfc *tensorFlowClassifier) runClassificationModel(imageInputTensor *tf.Tensor) (
 	results []*tf.Tensor,
 	predictions [][]float32,
 	err error) {
 
-	/** SavedModel **/
-	if tfc.savedModel != nil {
-		results = tfc.savedModel.Exec(
-			[]tf.Output{
-				tfc.savedModel.Op(tfc.config.OutputTag, 0),
-			}, map[tf.Output]*tf.Tensor{
-				tfc.savedModel.Op(tfc.config.InputTag, 0): imageInputTensor,
-			},
-		)
-		predictions = results[0].Value().([][]float32)
-		/** Frozen graph **/
-	} else if tfc.frozenModel != nil {
-		graph := tfc.frozenModel
-
-		session, err := tf.NewSession(graph, nil)
-		if err != nil {
-			return nil, nil, err
-		}
-		defer session.Close()
+	if err := tfc.verifyModelLoaded(); err != nil {
+		tfc.log.Errorln(err.Error())
+		return nil, nil, err
+	}
+
+	results, err = tfc.session.Run(
+		map[tf.Output]*tf.Tensor{tfc.input: imageInputTensor},
+		[]tf.Output{tfc.probabilities},
+		nil)
+	if err != nil {
+		return nil, nil, err
+	}
 
-		input := graph.Operation(tfc.config.InputTag).Output(0)
-		output := graph.Operation(tfc.config.OutputTag).Output(0)
+	predictions, ok := results[0].Value().([][]float32)
+	if !ok {
+		return nil, nil, errors.New(fmt.Sprintf("output %s of the model has shape %v, expected [batch, classes]",
+			tfc.config.OutputTag, results[0].Shape()))
+	}
+	return results, predictions, nil
+}
 
-		results, err = session.Run(
-			map[tf.Output]*tf.Tensor{input: imageInputTensor},
-			[]tf.Output{output},
-			nil)
-		if err != nil {
-			return nil, nil, err
-		}
+// verifyModelLoaded checks that a model is loaded and its input and output were found.
+func (tfc *tensorFlowClassifier) verifyModelLoaded() error {
+	if tfc.session == nil {
+		return errors.New("model must be loaded before starting classification")
+	}
+	if tfc.input.Op == nil {
+		return errors.New(fmt.Sprintf("input %s not found in model, run inspectModel to list the inputs of the model",
+			tfc.config.InputTag))
+	}
+	if tfc.probabilities.Op == nil {
+		return errors.New(fmt.Sprintf("output %s not found in model, run inspectModel to list the outputs of the model",
+			tfc.config.OutputTag))
+	}
+	return nil
+}
 
-		predictions = results[0].Value().([][]float32)
-	} else {
-		err := errors.New("model must be loaded before starting classification")
+// runTopKClassificationModel runs the loaded model and the top k operation on the given
+// input tensor in one session run.
+func (tfc *tensorFlowClassifier) runTopKClassificationModel(imageInputTensor *tf.Tensor, k int) (
+	values [][]float32,
+	indices [][]int32,
+	err error) {
+
+	if err := tfc.verifyModelLoaded(); err != nil {
 		tfc.log.Errorln(err.Error())
 		return nil, nil, err
 	}
 
-	return results, predictions, nil
+	kTensor, err := tf.NewTensor(int32(k))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	results, err := tfc.session.Run(
+		map[tf.Output]*tf.Tensor{tfc.input: imageInputTensor, tfc.topKInput: kTensor},
+		[]tf.Output{tfc.topKValues, tfc.topKIndices},
+		nil)
+	if err != nil {
+		tfc.log.WithError(err).Errorln("error during run of classifier model")
+		return nil, nil, err
+	}
+
+	values, ok := results[0].Value().([][]float32)
+	if !ok {
+		return nil, nil, errors.New(fmt.Sprintf("output %s of the model has shape %v, expected [batch, classes]",
+			tfc.config.OutputTag, results[0].Shape()))
+	}
+	return values, results[1].Value().([][]int32), nil
 }
 
 // loadLabels will load the imagenet label from a given textfile (defined in the LabelFile constant).
@@ -298,144 +662,59 @@ func (tfc *tensorFlowClassifier) loadLabels() (err error) {
 	return nil
 }
 
-func (tfc *tensorFlowClassifier) loadTensorFromImage(name string) (*tf.Tensor, error) {
-	rgbaImg, _, err := tfc.loadRGBAImage(name)
-	if err != nil {
-		tfc.log.WithError(err).Errorln("error loading image")
-		return nil, err
-	}
-
-	/*tensor, err := makeTensorFromImage(rgbaImg)
-	if err != nil {
-
-		log.Errorf("Error creating tensor from image: %s \n", err.Error())
-		return nil, err
-	}*/
-
-	tensor, err := vggPreprocessing.VGGPreprocessingForEval(rgbaImg, 224, 224, 224)
-	if err != nil {
-			tfc.log.WithError(err).Errorln("error during preprocessing of image")
+// loadBatchTensorFromImages preprocesses all images and stacks them into a single NHWC tensor.
+func (tfc *tensorFlowClassifier) loadBatchTensorFromImages(names []string) (*tf.Tensor, error) {
+	tensors := make([]*preprocessing.Tensor, 0, len(names))
+	for _, name := range names {
+		tensor, err := tfc.loadTensorFromImage(name)
+		if err != nil {
+			return nil, err
+		}
+		tensors = append(tensors, tensor)
 	}
 
-	return tensor, err
+	return tfc.makeBatchTensor(tensors)
 }
 
-func (tfc *tensorFlowClassifier) loadRGBAImage(filename string) (rgbaImage *image.RGBA, format string, err error) {
-	tfc.log.WithField("file", filename).Infoln("loading image")
-
-	file, err := os.Open(filename)
-	if err != nil {
-		tfc.log.WithField("file", filename).WithError(err).Errorln("could not open file")
-		return nil, "", err
+// makeBatchTensor stacks the preprocessed images into a single NHWC tensor.
+func (tfc *tensorFlowClassifier) makeBatchTensor(tensors []*preprocessing.Tensor) (*tf.Tensor, error) {
+	height, width := tfc.preprocessor.Size()
+	var batch bytes.Buffer
+	for _, tensor := range tensors {
+		batch.Write(tensor.Bytes())
 	}
-	defer file.Close()
 
-	// TODO: check if image is kept in ram
-	img, format, err := image.Decode(file)
-	if err != nil {
-		tfc.log.WithField("file", filename).WithError(err).Errorln("could not decode image")
-		return nil, "", err
-	}
-
-	rect := img.Bounds()
-	rgbaImg := image.NewRGBA(rect)
-	draw.Draw(rgbaImg, rect, img, rect.Min, draw.Src)
-
-	return rgbaImg, format, nil
+	return tf.ReadTensor(tf.Float, []int64{int64(len(tensors)), int64(height), int64(width), 3}, &batch)
 }
 
-func topKPredictions(predictions *tf.Tensor, k int) (val, idx *tf.Tensor, err error) {
-	graph, input, values, indices, err := makeTopKGraph(k)
-	if err != nil {
-		return nil, nil, err
-	}
-	session, err := tf.NewSession(graph, nil)
-	if err != nil {
-		return nil, nil, err
-	}
-	defer session.Close()
-	output, err := session.Run(
-		map[tf.Output]*tf.Tensor{input: predictions},
-		[]tf.Output{values, indices},
-		nil)
+func (tfc *tensorFlowClassifier) loadTensorFromImage(name string) (*preprocessing.Tensor, error) {
+	tensors, err := tfc.loadCropsFromImage(name, preprocessing.AugmentationNone)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
-	return output[0], output[1], err
-
+	return tensors[0], nil
 }
 
-func makeTopKGraph(k int) (graph *tf.Graph, input, values, indices tf.Output, err error) {
-	s := op.NewScope()
-
-	input = op.Placeholder(s, tf.Float)
-	values, indices = op.TopKV2(s, input, tg.Const(s, int32(k)))
-	graph, err = s.Finalize()
+// loadCropsFromImage preprocesses the image once for every crop of the augmentation.
+func (tfc *tensorFlowClassifier) loadCropsFromImage(name string, augmentation string) ([]*preprocessing.Tensor, error) {
+	if tfc.preprocessor == nil {
+		return nil, errors.New("no preprocessing configured")
+	}
 
-	return graph, input, values, indices, err
-}
+	tfc.log.WithField("file", name).Infoln("loading image")
 
-// based on https://outcrawl.com/image-recognition-api-go-tensorflow
-func makeTensorFromImage(image *image.RGBA) (*tf.Tensor, error) {
-	tensor, err := tf.NewTensor(image.Pix)
+	img, err := preprocessing.LoadImage(name)
 	if err != nil {
+		tfc.log.WithField("file", name).WithError(err).Errorln("error loading image")
 		return nil, err
 	}
-	graph, input, output, err := makeTransformImageGraph(&image.Rect)
-	if err != nil {
-		return nil, err
-	}
-	session, err := tf.NewSession(graph, nil)
-	if err != nil {
-		return nil, err
-	}
-	defer session.Close()
-	normalized, err := session.Run(
-		map[tf.Output]*tf.Tensor{input: tensor},
-		[]tf.Output{output},
-		nil)
+
+	tensors, err := preprocessing.Augment(tfc.preprocessor, img, augmentation)
 	if err != nil {
-		return nil, err
+		tfc.log.WithField("file", name).WithError(err).Errorln("error during preprocessing of image")
 	}
-	return normalized[0], nil
-}
-
-func makeTransformImageGraph(rect *image.Rectangle) (graph *tf.Graph, input, output tf.Output, err error) {
-	const (
-		H, W  = 224, 224
-		Mean  = float32(117) // was 117
-		Scale = float32(1)
-	)
-	s := op.NewScope()
-	//TODO: refactor
-	input = op.Placeholder(s, tf.Uint8)
-
-	height := rect.Max.Y - rect.Min.Y
-	width := rect.Max.X - rect.Min.X
-
-	img := op.Reshape(s, op.Cast(s, input, tf.Float), tg.Const(s, [3]int32{int32(height), int32(width), 4}))
-	// remove alpha channel
-	img = op.Slice(s, img, tg.Const(s, [3]int32{0, 0, 0}), tg.Const(s, [3]int32{int32(height), int32(width), 3}))
-	// Create a batch containing a single image
-	batch := op.ExpandDims(s,
-		// Use pixel values
-		img,
-		op.Const(s.SubScope("make_batch"), int32(0)))
-
-	// Resize to 224x224 with bilinear interpolation
-	resizedBatch := op.ResizeBilinear(s,
-		batch,
-		op.Const(s.SubScope("size"), []int32{H, W}))
-
-	// Div and Sub perform (value-Mean)/Scale for each pixel
-	output = op.Div(s,
-		op.Sub(s,
-			resizedBatch,
-			op.Const(s.SubScope("mean"), Mean)),
-		op.Const(s.SubScope("scale"), Scale))
-
-	graph, err = s.Finalize()
-	return graph, input, output, err
+
+	return tensors, err
 }
 
 func dummyInputBatch(numImages int, height int, width int, channels int) (*tf.Tensor, error) {
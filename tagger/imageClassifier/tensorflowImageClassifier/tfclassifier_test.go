@@ -4,6 +4,7 @@ import (
 	"fmt"
 	log "github.com/sirupsen/logrus"
 	"os"
+	"path"
 	"runtime"
 	"strings"
 	"testing"
@@ -24,9 +25,19 @@ func TestTensorFlowClassifier_GetTopKPredictionsForImage_VGGModel(t *testing.T)
 	imageFilename := "../../../data/pics/Bus-en.jpg"
 	k := 10
 
-	tfc := New("../../../data/tensorflowModels/", logger)
+	dataPath := func(resource string) string {
+		return path.Join("../../../data/tensorflowModels/", resource)
+	}
+	tfc := New(dataPath, logger, Config{
+		InputTag:  "input",
+		OutputTag: "vgg_19/fc8/squeezed",
+		LabelFile: "imagenet_comp_graph_label_strings.txt",
+		LabelOffset: 1,
+		NumLabels: 1000,
+	})
+	defer tfc.Close()
 
-	err := tfc.LoadFrozenModel(VGGModel)
+	err := tfc.LoadFrozenModel("frozen_vgg_19.pb")
 	if err != nil {
 		t.Fail()
 	}
@@ -197,7 +197,7 @@ func _mean_image_subtraction(s *op.Scope, image *tf.Output, means []float32) (*t
 	log.Println(s.Err())
 	for i, _ := range channels {
 		scopeName := fmt.Sprintf("channel%d", i)
-		op.Sub(s.SubScope(scopeName), channels[i], tg.Const(s, means[i]))
+		channels[i] = op.Sub(s.SubScope(scopeName), channels[i], tg.Const(s.SubScope(scopeName), means[i]))
 	}
 	resultImage := op.Concat(s, tg.Const(s, int32(2)), channels)
 	return &resultImage, nil
@@ -0,0 +1,47 @@
+package vggPreprocessing
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/twatzl/imtag/tagger/imageClassifier/preprocessing"
+)
+
+// the pure Go preprocessing has to produce the same input for the models as the TensorFlow graph
+func TestVGGPreprocessingForEval_MatchesGoPreprocessing(t *testing.T) {
+	const tolerance = 1e-3
+
+	img := image.NewRGBA(image.Rect(0, 0, 317, 240))
+	for y := 0; y < 240; y++ {
+		for x := 0; x < 317; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), uint8(x * y), 255})
+		}
+	}
+
+	tfTensor, err := VGGPreprocessingForEval(img, 224, 224, RESIZE_SIDE_MIN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tfImage := tfTensor.Value().([][][][]float32)[0]
+
+	p, err := preprocessing.New(preprocessing.VGG, 224, 224)
+	if err != nil {
+		t.Fatal(err)
+	}
+	goTensor, err := p.Preprocess(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for y := range tfImage {
+		for x := range tfImage[y] {
+			for c := range tfImage[y][x] {
+				if diff := math.Abs(float64(tfImage[y][x][c] - goTensor.At(y, x, c))); diff > tolerance {
+					t.Fatalf("difference of %v at %d,%d,%d", diff, y, x, c)
+				}
+			}
+		}
+	}
+}
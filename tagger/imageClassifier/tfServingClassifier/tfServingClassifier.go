@@ -0,0 +1,285 @@
+// The package tfServingClassifier implements an image classifier which uses a model served by
+// TensorFlow Serving. It uses the predict API of the REST interface:
+// https://www.tensorflow.org/tfx/serving/api_rest#predict_api
+//
+// The images are preprocessed locally and sent in the row format ("instances"). The labels are read
+// from a local label file, since the server only returns the output tensor of the model.
+package tfServingClassifier
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"github.com/twatzl/imtag/tagger/image"
+	"github.com/twatzl/imtag/tagger/imageClassifier"
+	"github.com/twatzl/imtag/tagger/imageClassifier/preprocessing"
+	"github.com/twatzl/imtag/tagger/tag"
+	"strings"
+	"time"
+)
+
+const defaultBatchSize = 16
+
+type TFServingClassifier interface {
+	imageClassifier.ImageClassifier
+}
+
+type tfServingClassifier struct {
+	dataPathForResource func(string) string
+	log                 *logrus.Logger
+	labels              []string
+	synsetIds           []string
+	config              Config
+	client              *imageClassifier.JSONClient
+	preprocessor        preprocessing.Preprocessor
+}
+
+type Config struct {
+	/* the base url of the rest api of the server, e.g. http://localhost:8501 */
+	URL string
+	/* the name under which the model is served */
+	ModelName string
+	/* the version of the model, if 0 the latest version is used */
+	ModelVersion int64
+	/* the signature of the model (default is serving_default) */
+	Signature string
+	/* the name of the input of the signature. only needed if the signature has multiple inputs */
+	InputTag string
+	/* the name of the output of the signature. only needed if the signature has multiple outputs */
+	OutputTag string
+	/* the name of the file which contains the label mappings */
+	LabelFile string
+	/* the line of the label file which belongs to the first output of the model. 1 skips a "dummy" line,
+	   -1 adds a background label for models with a background class which is not in the label file */
+	LabelOffset int
+	/* the file with the WordNet ids of the labels in the same order as the label file (optional) */
+	WnidLabelFile string
+	/* the number of labels (or classes) the model outputs */
+	NumLabels int
+	/* the name of the preprocessing which was used during training of the model (e.g. vgg or inception) */
+	Preprocessing string
+	/* the size of the images the model expects as input */
+	InputHeight int
+	InputWidth  int
+	/* whether the model outputs probabilities or logits (see the Output constants in imageClassifier) */
+	OutputType string
+	/* the temperature with which the output is normalized, see the calibrate command (0 means 1) */
+	Temperature float32
+	/* the test-time augmentation, the probabilities of the crops are averaged (see the Augmentation
+	   constants in preprocessing) */
+	Augmentation string
+	/* the timeout for a single request */
+	Timeout time.Duration
+	/* how often a failed request is repeated, see imageClassifier.Retries */
+	Retries *int
+	/* the number of images which are sent in one request */
+	BatchSize int
+}
+
+type predictRequest struct {
+	SignatureName string        `json:"signature_name,omitempty"`
+	Instances     []interface{} `json:"instances"`
+}
+
+type predictResponse struct {
+	Predictions []json.RawMessage `json:"predictions"`
+}
+
+func New(dataPathMapper func(string) string, log *logrus.Logger, config Config) TFServingClassifier {
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultBatchSize
+	}
+
+	classifier := &tfServingClassifier{
+		dataPathForResource: dataPathMapper,
+		log:                 log,
+		config:              config,
+		client:              imageClassifier.NewJSONClient(log, config.Timeout, config.Retries),
+	}
+
+	var err error
+	classifier.labels, err = imageClassifier.LoadLabels(dataPathMapper(config.LabelFile))
+	if err != nil {
+		log.WithError(err).Errorln("could not load labels")
+	}
+
+	if config.WnidLabelFile != "" {
+		classifier.synsetIds, err = imageClassifier.LoadSynsetIds(dataPathMapper(config.WnidLabelFile))
+		if err != nil {
+			log.WithError(err).Errorln("could not load synset ids of labels")
+		}
+	}
+
+	// the labels and synset ids are aligned with the outputs of the model
+	classifier.synsetIds = imageClassifier.AlignSynsetIds(classifier.synsetIds, len(classifier.labels), config.LabelOffset)
+	classifier.labels = imageClassifier.ApplyLabelOffset(classifier.labels, config.LabelOffset, imageClassifier.BackgroundLabel)
+
+	classifier.preprocessor, err = preprocessing.New(config.Preprocessing, config.InputHeight, config.InputWidth)
+	if err != nil {
+		log.WithError(err).Errorln("could not create preprocessing")
+	}
+
+	return classifier
+}
+
+// PredictURL returns the url of the predict endpoint for the configured model and version.
+func (c Config) PredictURL() string {
+	url := fmt.Sprintf("%s/v1/models/%s", strings.TrimSuffix(c.URL, "/"), c.ModelName)
+	if c.ModelVersion > 0 {
+		url = fmt.Sprintf("%s/versions/%d", url, c.ModelVersion)
+	}
+	return url + ":predict"
+}
+
+func (tsc *tfServingClassifier) ClassifyImages(images []image.Image) ([][]tag.Tag, error) {
+	return tsc.classifyImagesInBatches(images, 0)
+}
+
+func (tsc *tfServingClassifier) ClassifyImagesTopK(images []image.Image, k int) ([][]tag.Tag, error) {
+	return tsc.classifyImagesInBatches(images, k)
+}
+
+// classifyImagesInBatches sends the images in batches of the configured size to the server. Images
+// which can not be loaded get an error and no tags.
+func (tsc *tfServingClassifier) classifyImagesInBatches(images []image.Image, k int) ([][]tag.Tag, error) {
+	if tsc.preprocessor == nil {
+		return nil, errors.New("no preprocessing configured")
+	}
+	if err := imageClassifier.VerifyLabelCount(tsc.labels, tsc.config.NumLabels); err != nil {
+		return nil, err
+	}
+	if tsc.config.WnidLabelFile != "" {
+		if err := imageClassifier.VerifySynsetIdCount(tsc.synsetIds, tsc.labels); err != nil {
+			return nil, err
+		}
+	}
+
+	tags := make([][]tag.Tag, len(images))
+	for start := 0; start < len(images); start += tsc.config.BatchSize {
+		end := start + tsc.config.BatchSize
+		if end > len(images) {
+			end = len(images)
+		}
+
+		req := predictRequest{SignatureName: tsc.config.Signature}
+		// positions holds the index of each sent image in the images slice, crops the number of
+		// instances of each sent image
+		positions := make([]int, 0, end-start)
+		crops := make([]int, 0, end-start)
+		for i := start; i < end; i++ {
+			instances, err := tsc.makeInstances(images[i].GetFilename())
+			if err != nil {
+				tsc.log.WithField("file", images[i].GetFilename()).WithError(err).Errorln("error loading image")
+				images[i].SetError(err)
+				continue
+			}
+			req.Instances = append(req.Instances, instances...)
+			positions = append(positions, i)
+			crops = append(crops, len(instances))
+		}
+
+		if len(req.Instances) == 0 {
+			continue
+		}
+
+		predictions, err := tsc.predict(req)
+		if err != nil {
+			return tags, err
+		}
+		if len(predictions) != len(req.Instances) {
+			return tags, errors.New(fmt.Sprintf("server returned %d predictions for %d instances", len(predictions), len(req.Instances)))
+		}
+
+		for i, prediction := range predictions {
+			if err := imageClassifier.VerifyOutputSize(len(prediction), tsc.config.NumLabels); err != nil {
+				return tags, err
+			}
+			predictions[i] = imageClassifier.ToProbabilities(tsc.config.OutputType, tsc.config.Temperature, prediction)
+		}
+
+		for i, prediction := range imageClassifier.AverageCrops(predictions, crops) {
+			imageTags := imageClassifier.PredictionsToTags(tsc.labels, tsc.synsetIds, prediction)
+			if k > 0 {
+				imageTags = imageClassifier.TopKTags(imageTags, k)
+			}
+			tags[positions[i]] = imageTags
+		}
+	}
+
+	return tags, nil
+}
+
+// makeInstances preprocesses the image and converts each crop of the augmentation into an
+// instance of the predict request.
+func (tsc *tfServingClassifier) makeInstances(filename string) ([]interface{}, error) {
+	img, err := preprocessing.LoadImage(filename)
+	if err != nil {
+		return nil, err
+	}
+	tensors, err := preprocessing.Augment(tsc.preprocessor, img, tsc.config.Augmentation)
+	if err != nil {
+		return nil, err
+	}
+
+	instances := make([]interface{}, len(tensors))
+	for i, tensor := range tensors {
+		instances[i] = tsc.makeInstance(tensor)
+	}
+	return instances, nil
+}
+
+// makeInstance converts the tensor into an instance of the predict request. If an input tag is
+// configured the instance is an object with the tensor as named input.
+func (tsc *tfServingClassifier) makeInstance(tensor *preprocessing.Tensor) interface{} {
+
+	values := make([][][]float32, tensor.Height)
+	for y := range values {
+		values[y] = make([][]float32, tensor.Width)
+		for x := range values[y] {
+			offset := (y*tensor.Width + x) * tensor.Channels
+			values[y][x] = tensor.Data[offset : offset+tensor.Channels]
+		}
+	}
+
+	if tsc.config.InputTag != "" {
+		return map[string]interface{}{tsc.config.InputTag: values}
+	}
+	return values
+}
+
+// predict sends the request to the server and returns the output vector for each instance.
+func (tsc *tfServingClassifier) predict(req predictRequest) ([][]float32, error) {
+	resp := &predictResponse{}
+	if err := tsc.client.Post(tsc.config.PredictURL(), req, resp); err != nil {
+		return nil, err
+	}
+	return tsc.decodePredictions(resp)
+}
+
+// decodePredictions extracts the output vectors from the predictions. If the signature has multiple
+// outputs each prediction is an object and the configured output is used.
+func (tsc *tfServingClassifier) decodePredictions(resp *predictResponse) ([][]float32, error) {
+	predictions := make([][]float32, len(resp.Predictions))
+	for i, raw := range resp.Predictions {
+		if tsc.config.OutputTag == "" {
+			if err := json.Unmarshal(raw, &predictions[i]); err != nil {
+				return nil, errors.New(fmt.Sprintf("could not decode prediction, if the model has multiple outputs one has to be configured: %s", err.Error()))
+			}
+			continue
+		}
+
+		outputs := map[string]json.RawMessage{}
+		if err := json.Unmarshal(raw, &outputs); err != nil {
+			return nil, err
+		}
+		output, ok := outputs[tsc.config.OutputTag]
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("output %s not found in prediction", tsc.config.OutputTag))
+		}
+		if err := json.Unmarshal(output, &predictions[i]); err != nil {
+			return nil, err
+		}
+	}
+	return predictions, nil
+}
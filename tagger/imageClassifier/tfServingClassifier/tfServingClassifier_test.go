@@ -0,0 +1,92 @@
+package tfServingClassifier
+
+import (
+	"encoding/json"
+	"github.com/sirupsen/logrus"
+	"github.com/twatzl/imtag/tagger/image"
+	goimage "image"
+	"image/png"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestConfig_PredictURL(t *testing.T) {
+	c := Config{URL: "http://localhost:8501/", ModelName: "resnet"}
+	if url := c.PredictURL(); url != "http://localhost:8501/v1/models/resnet:predict" {
+		t.Errorf("unexpected url %s", url)
+	}
+
+	c.ModelVersion = 3
+	if url := c.PredictURL(); url != "http://localhost:8501/v1/models/resnet/versions/3:predict" {
+		t.Errorf("unexpected url %s", url)
+	}
+}
+
+func TestTFServingClassifier_ClassifyImagesTopK(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tfServingClassifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = ioutil.WriteFile(path.Join(dir, "labels.txt"), []byte("cat\ndog\nbus\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imageFile, err := os.Create(path.Join(dir, "image.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	png.Encode(imageFile, goimage.NewRGBA(goimage.Rect(0, 0, 8, 8)))
+	imageFile.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models/test/versions/2:predict" {
+			http.Error(w, `{"error": "unknown model"}`, http.StatusNotFound)
+			return
+		}
+
+		var req struct {
+			Instances []map[string][][][]float32 `json:"instances"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		if len(req.Instances) != 1 || len(req.Instances[0]["images"]) != 4 {
+			t.Errorf("expected one 4x4 instance with named input")
+		}
+
+		w.Write([]byte(`{"predictions": [{"probabilities": [0.2, 0.7, 0.1], "classes": 1}]}`))
+	}))
+	defer server.Close()
+
+	classifier := New(func(resource string) string { return path.Join(dir, resource) }, logrus.New(), Config{
+		URL:           server.URL,
+		ModelName:     "test",
+		ModelVersion:  2,
+		InputTag:      "images",
+		OutputTag:     "probabilities",
+		LabelFile:     "labels.txt",
+		NumLabels:     3,
+		Preprocessing: "resize",
+		InputHeight:   4,
+		InputWidth:    4,
+	})
+
+	images := []image.Image{image.New(path.Join(dir, "image.png")), image.New(path.Join(dir, "labels.txt"))}
+	tags, err := classifier.ClassifyImagesTopK(images, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tags[0]) != 2 || tags[0][0].GetLabel() != "dog" || tags[0][1].GetLabel() != "cat" {
+		t.Errorf("unexpected tags %v", tags[0])
+	}
+	if images[1].GetError() == nil {
+		t.Errorf("expected error for file which is not an image")
+	}
+}
@@ -3,18 +3,30 @@ package tagger
 import (
 	"github.com/fluhus/gostuff/nlp/wordnet"
 	"github.com/twatzl/imtag/tagger/image"
+	"github.com/twatzl/imtag/tagger/tag"
 	"github.com/twatzl/imtag/tagger/word2vec"
 )
 
-func embedImageFlat(word2vec word2vec.Word2Vec, image image.Image) []float32 {
+// embedImageFlat embeds the image as the average of the vectors of its tags weighted by their
+// confidence. Tags which can not be embedded are left out, also from the normalizer Z.
+func embedImageFlat(word2vec word2vec.Word2Vec, wordnet *wordnet.WordNet, image image.Image) []float32 {
 	tags := image.GetTags()
 	var sumProbabilities float32 = 0.0 // this corresponds to Z in the paper
 	embeddedVector := make([]float32, word2vec.GetDim())
 
-	for _,tag := range tags {
-		label := tag.GetLabel()
+	// all tags are looked up at once, so the model is only read once per image
+	words := make([]string, len(tags))
+	for i, tag := range tags {
+		words[i] = tagWord(word2vec, wordnet, tag)
+	}
+	vectors := word2vec.Word2VecBatch(words)
+
+	for i,tag := range tags {
 		confidence := tag.GetConfidence()
-		vec := word2vec.Word2Vec(label)
+		vec := vectors[i]
+		if vec == nil {
+			continue
+		}
 
 		for idx, val := range vec {
 			embeddedVector[idx] += val * confidence
@@ -23,6 +35,9 @@ func embedImageFlat(word2vec word2vec.Word2Vec, image image.Image) []float32 {
 		sumProbabilities += confidence
 	}
 
+	if sumProbabilities == 0 {
+		return embeddedVector
+	}
 	sumProbabilities = 1/sumProbabilities
 
 	for idx, val := range embeddedVector {
@@ -37,3 +52,37 @@ func embedImageHierarchical(word2vec word2vec.Word2Vec, wordnet *wordnet.WordNet
 	// TODO
 
 }
+
+// tagWord returns the word which is looked up to embed a tag. Tags with a synset id are embedded by
+// the words of the synset, otherwise or if none of them is known the label is used.
+func tagWord(word2vec word2vec.Word2Vec, wordnet *wordnet.WordNet, t tag.Tag) string {
+	if synset := synsetForTag(wordnet, t); synset != nil {
+		if word, ok := synsetWord(word2vec, synset); ok {
+			return word
+		}
+	}
+
+	return t.GetLabel()
+}
+
+// EmbedSynset returns the word2vec vector of the first word of the synset which is known to
+// the word2vec model or nil if none of the words is known.
+func EmbedSynset(word2vec word2vec.Word2Vec, synset *wordnet.Synset) []float32 {
+	word, ok := synsetWord(word2vec, synset)
+	if !ok {
+		return nil
+	}
+
+	return word2vec.Word2Vec(word)
+}
+
+// synsetWord returns the first word of the synset which is known to the word2vec model.
+func synsetWord(word2vec word2vec.Word2Vec, synset *wordnet.Synset) (string, bool) {
+	for _, word := range synset.Word {
+		if word2vec.Contains(word) {
+			return word, true
+		}
+	}
+
+	return "", false
+}
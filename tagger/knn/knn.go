@@ -13,6 +13,11 @@ type knnMapping struct {
 
 type KnnDistFunc func([]float32, []float32) float32
 
+type Neighbor struct {
+	Label    label.Label
+	Distance float32
+}
+
 /**
  * KnnSearch will search the vectorspace (given by all labels which have been embedded in the vectorspace) and
  * return the k labels with the smallest distance to the embedded image.
@@ -20,7 +25,28 @@ type KnnDistFunc func([]float32, []float32) float32
  * or by using goroutines to utilize multicore systems.
  */
 func KnnSearch(vectorspace []label.Label, searchTarget [][]float32, k int, distanceFunction KnnDistFunc) [][]label.Label {
+	neighbors := KnnSearchWithDistances(vectorspace, searchTarget, k, distanceFunction)
+
+	result := make([][]label.Label, len(neighbors))
+	for targetIdx := range neighbors {
+		result[targetIdx] = make([]label.Label, len(neighbors[targetIdx]))
+		for i, n := range neighbors[targetIdx] {
+			result[targetIdx][i] = n.Label
+		}
+	}
+
+	return result
+}
+
+/**
+ * KnnSearchWithDistances works the same as KnnSearch, but additionally returns the distance
+ * of each of the labels to the search target.
+ */
+func KnnSearchWithDistances(vectorspace []label.Label, searchTarget [][]float32, k int, distanceFunction KnnDistFunc) [][]Neighbor {
 	var distances = make([][]knnMapping, len(searchTarget))
+	for targetIdx := range searchTarget {
+		distances[targetIdx] = make([]knnMapping, len(vectorspace))
+	}
 
 	// calculate distances to all elements in vectorspace
 	for labelIdx, l := range vectorspace {
@@ -32,7 +58,7 @@ func KnnSearch(vectorspace []label.Label, searchTarget [][]float32, k int, dista
 		}
 	}
 
-	result := make([][]label.Label, len(searchTarget))
+	result := make([][]Neighbor, len(searchTarget))
 
 	// sort result, take first k elements and map them back to labels
 	for targetIdx := range searchTarget {
@@ -40,7 +66,6 @@ func KnnSearch(vectorspace []label.Label, searchTarget [][]float32, k int, dista
 		sort.Slice(distances[targetIdx], func(i, j int) bool {
 			return  distances[targetIdx][i].distance < distances[targetIdx][j].distance
 		})
-		distances[targetIdx] = distances[targetIdx][:k]
 
 		// take min of k or length
 		x := len(distances[targetIdx])
@@ -48,11 +73,12 @@ func KnnSearch(vectorspace []label.Label, searchTarget [][]float32, k int, dista
 			x = k
 		}
 
-		result[targetIdx] = make([]label.Label, x)
+		result[targetIdx] = make([]Neighbor, x)
 
 		// mapping results to labels
 		for i := 0; i < x; i++ {
-			result[targetIdx][i] = vectorspace[distances[targetIdx][i].labelIndex]
+			mapping := distances[targetIdx][i]
+			result[targetIdx][i] = Neighbor{vectorspace[mapping.labelIndex], mapping.distance}
 		}
 	}
 
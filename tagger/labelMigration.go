@@ -0,0 +1,36 @@
+package tagger
+
+import (
+	"github.com/twatzl/imtag/tagger/wordnetMapping"
+)
+
+/**
+ * MigrateLabels translates all synset ids stored in the source label storage into the WordNet version
+ * described by the mapping and stores them in the target label storage. Source and target may be the
+ * same storage. Labels which could not be mapped are not stored in the target and are returned, so
+ * they can be reported to the user.
+ */
+func MigrateLabels(source LabelStorage, target LabelStorage, mapping wordnetMapping.Mapping) (unmapped []string, err error) {
+	labels, err := source.LoadLabelsSlice()
+	if err != nil {
+		return nil, err
+	}
+
+	migrated := make(map[string]interface{})
+	for _, l := range labels {
+		if l == "" {
+			continue
+		}
+
+		synsetId, ok := mapping.Map(l)
+		if !ok {
+			unmapped = append(unmapped, l)
+			continue
+		}
+
+		migrated[synsetId] = ""
+	}
+
+	err = target.StoreLabels(migrated)
+	return unmapped, err
+}
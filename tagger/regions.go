@@ -0,0 +1,180 @@
+package tagger
+
+import (
+	"fmt"
+	"github.com/twatzl/imtag/tagger/image"
+	"github.com/twatzl/imtag/tagger/imageClassifier/preprocessing"
+	"github.com/twatzl/imtag/tagger/tag"
+	goimage "image"
+	"image/draw"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// tiledImage holds the regions of an image which are classified in addition to the whole image.
+type tiledImage struct {
+	img     image.Image
+	bounds  []goimage.Rectangle
+	regions []image.Image
+}
+
+// tileGrid splits the bounds into a grid of tiles x tiles regions. Neighbouring regions overlap
+// by the given fraction of their size, so objects on the border of two regions are not cut.
+func tileGrid(bounds goimage.Rectangle, tiles int, overlap float64) []goimage.Rectangle {
+	if tiles <= 0 {
+		return nil
+	}
+
+	// n tiles of size s with overlap o cover n*s - (n-1)*o*s
+	scale := float64(tiles) - float64(tiles-1)*overlap
+	width := int(float64(bounds.Dx()) / scale)
+	height := int(float64(bounds.Dy()) / scale)
+	stepX := float64(width) * (1 - overlap)
+	stepY := float64(height) * (1 - overlap)
+
+	regions := make([]goimage.Rectangle, 0, tiles*tiles)
+	for row := 0; row < tiles; row++ {
+		for col := 0; col < tiles; col++ {
+			x := bounds.Min.X + int(float64(col)*stepX)
+			y := bounds.Min.Y + int(float64(row)*stepY)
+			// the last row and column end at the border, rounding must not leave a gap
+			maxX, maxY := x+width, y+height
+			if col == tiles-1 {
+				maxX = bounds.Max.X
+			}
+			if row == tiles-1 {
+				maxY = bounds.Max.Y
+			}
+			regions = append(regions, goimage.Rect(x, y, maxX, maxY))
+		}
+	}
+	return regions
+}
+
+// classifyRegions splits each image into a grid of regions and classifies them. The classifiers
+// load images by their file name, so the regions are written to a temporary directory which is
+// removed by cleanup. Images which can not be split have no regions.
+func (t *tagger) classifyRegions(images []image.Image) (tiled []tiledImage, cleanup func(), err error) {
+	dir, err := ioutil.TempDir("", "imtag-regions")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup = func() {
+		os.RemoveAll(dir)
+	}
+
+	regionImages := []image.Image{}
+	tiled = make([]tiledImage, len(images))
+	for i, img := range images {
+		tiled[i].img = img
+
+		src, err := preprocessing.LoadImage(img.GetFilename())
+		if err != nil {
+			t.logger.WithField("file", img.GetFilename()).WithError(err).Warnln("could not split image into regions")
+			continue
+		}
+
+		for j, bounds := range tileGrid(src.Bounds(), t.conf.Tiles, t.conf.TileOverlap) {
+			filename := path.Join(dir, fmt.Sprintf("%d_%d.png", i, j))
+			if err := writeRegion(src, bounds, filename); err != nil {
+				cleanup()
+				return nil, nil, err
+			}
+
+			region := image.New(filename)
+			tiled[i].bounds = append(tiled[i].bounds, bounds.Sub(src.Bounds().Min))
+			tiled[i].regions = append(tiled[i].regions, region)
+			regionImages = append(regionImages, region)
+		}
+	}
+
+	if len(regionImages) > 0 {
+		if err := t.classifyImages(regionImages, t.conf.K); err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+	}
+	return tiled, cleanup, nil
+}
+
+// writeRegion saves the part of the image within the bounds as png file.
+func writeRegion(src goimage.Image, bounds goimage.Rectangle, filename string) error {
+	region := goimage.NewRGBA(goimage.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(region, region.Bounds(), src, bounds.Min, draw.Src)
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return png.Encode(file, region)
+}
+
+// setRegions stores the regions with their tags in the image and merges the tags of the regions
+// into the tags of the image. Regions which could not be classified are left out.
+func (ti tiledImage) setRegions(k int) {
+	regions := make([]image.Region, 0, len(ti.regions))
+	tagLists := [][]tag.Tag{ti.img.GetTags()}
+	for i, region := range ti.regions {
+		if region.GetError() != nil {
+			continue
+		}
+
+		bounds := ti.bounds[i]
+		regions = append(regions, image.Region{
+			X:      bounds.Min.X,
+			Y:      bounds.Min.Y,
+			Width:  bounds.Dx(),
+			Height: bounds.Dy(),
+			Tags:   region.GetTags(),
+		})
+		tagLists = append(tagLists, region.GetTags())
+	}
+
+	ti.img.SetRegions(regions)
+	ti.img.SetTags(mergeTags(tagLists, k))
+}
+
+// mergeTags combines the tags of the whole image and its regions. A label found in several
+// regions keeps its highest confidence. The tags are sorted by confidence and at most k are
+// returned, all if k is 0.
+func mergeTags(tagLists [][]tag.Tag, k int) []tag.Tag {
+	best := map[string]tag.Tag{}
+	for _, tags := range tagLists {
+		for _, t := range tags {
+			key := tagKey(t)
+			if current, ok := best[key]; !ok || t.GetConfidence() > current.GetConfidence() {
+				best[key] = t
+			}
+		}
+	}
+
+	merged := make([]tag.Tag, 0, len(best))
+	for _, t := range best {
+		merged = append(merged, t)
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		if merged[i].GetConfidence() != merged[j].GetConfidence() {
+			return merged[i].GetConfidence() > merged[j].GetConfidence()
+		}
+		return merged[i].GetLabel() < merged[j].GetLabel()
+	})
+
+	if k > 0 && k < len(merged) {
+		merged = merged[:k]
+	}
+	return merged
+}
+
+// tagKey identifies the label of a tag by its synset id or, if it has none, by its name.
+func tagKey(t tag.Tag) string {
+	if t.GetSynsetId() != "" {
+		return t.GetSynsetId()
+	}
+	return strings.ToLower(t.GetLabel())
+}
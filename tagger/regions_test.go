@@ -0,0 +1,50 @@
+package tagger
+
+import (
+	"github.com/twatzl/imtag/tagger/tag"
+	goimage "image"
+	"testing"
+)
+
+func TestTileGrid(t *testing.T) {
+	bounds := goimage.Rect(0, 0, 500, 300)
+
+	regions := tileGrid(bounds, 2, 0.25)
+	if len(regions) != 4 {
+		t.Fatalf("expected 4 regions but got %d", len(regions))
+	}
+
+	// two regions with an overlap of a quarter cover 1.75 region widths
+	expected := []goimage.Rectangle{
+		goimage.Rect(0, 0, 285, 171),
+		goimage.Rect(213, 0, 500, 171),
+		goimage.Rect(0, 128, 285, 300),
+		goimage.Rect(213, 128, 500, 300),
+	}
+	for i := range expected {
+		if regions[i] != expected[i] {
+			t.Errorf("expected region %d to be %v but got %v", i, expected[i], regions[i])
+		}
+	}
+
+	if len(tileGrid(bounds, 0, 0.25)) != 0 {
+		t.Errorf("expected no regions if tiling is disabled")
+	}
+}
+
+func TestMergeTags(t *testing.T) {
+	merged := mergeTags([][]tag.Tag{
+		{tag.New("dog", 0.5), tag.New("grass", 0.3)},
+		{tag.New("Dog", 0.7), tag.New("ball", 0.4)},
+	}, 2)
+
+	if len(merged) != 2 {
+		t.Fatalf("expected 2 tags but got %d", len(merged))
+	}
+	if merged[0].GetLabel() != "Dog" || merged[0].GetConfidence() != 0.7 {
+		t.Errorf("expected Dog with 0.7 first but got %s with %f", merged[0].GetLabel(), merged[0].GetConfidence())
+	}
+	if merged[1].GetLabel() != "ball" {
+		t.Errorf("expected ball second but got %s", merged[1].GetLabel())
+	}
+}
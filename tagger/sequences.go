@@ -0,0 +1,254 @@
+package tagger
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mewkiz/pkg/osutil"
+	"github.com/twatzl/imtag/tagger/image"
+	"github.com/twatzl/imtag/tagger/imageClassifier"
+	"github.com/twatzl/imtag/tagger/tag"
+	goimage "image"
+	"image/draw"
+	"image/gif"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+)
+
+// Methods to aggregate the predictions of the frames of a sequence.
+const (
+	// SequenceMergeMean averages the confidence of each label over all frames.
+	SequenceMergeMean = "mean"
+	// SequenceMergeMax uses the highest confidence of each label in any frame.
+	SequenceMergeMax = "max"
+)
+
+// SequenceMarker is the name of the file which marks a directory as a sequence, e.g. a burst of
+// photos. The images of such a directory are tagged together.
+const SequenceMarker = ".sequence"
+
+// defaultMaxFrames is the number of frames which are sampled from a sequence if none is configured.
+const defaultMaxFrames = 8
+
+func isSequenceDir(dir string) bool {
+	return osutil.Exists(path.Join(dir, SequenceMarker))
+}
+
+func (t *tagger) maxFrames() int {
+	if t.conf.MaxFrames <= 0 {
+		return defaultMaxFrames
+	}
+	return t.conf.MaxFrames
+}
+
+// sampleFrames returns the indices of at most max frames evenly spread over a sequence of n frames.
+// The first and the last frame are always included.
+func sampleFrames(n int, max int) []int {
+	if n <= max {
+		max = n
+	}
+
+	indices := make([]int, 0, max)
+	if max == 1 {
+		return append(indices, n/2)
+	}
+	for i := 0; i < max; i++ {
+		indices = append(indices, i*(n-1)/(max-1))
+	}
+	return indices
+}
+
+// sequenceFromDir creates a sequence of the images in the directory, ordered by file name.
+func (t *tagger) sequenceFromDir(dir string) (image.Image, error) {
+	files, err := imageFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	sequence := image.New(dir)
+	if len(files) == 0 {
+		sequence.SetError(errors.New("sequence contains no images"))
+		return sequence, nil
+	}
+
+	frames := []image.Frame{}
+	for _, i := range sampleFrames(len(files), t.maxFrames()) {
+		frames = append(frames, image.Frame{Index: i, Image: image.New(files[i])})
+	}
+	sequence.SetFrames(frames)
+	return sequence, nil
+}
+
+// splitAnimatedGIFs samples the frames of animated GIFs and stores them as frames of the images.
+// The classifiers load images by their file name, so the frames are written to a temporary
+// directory which is removed by cleanup. GIFs with a single frame are classified as usual.
+func (t *tagger) splitAnimatedGIFs(images []image.Image) (cleanup func(), err error) {
+	dir := ""
+	cleanup = func() {
+		if dir != "" {
+			os.RemoveAll(dir)
+		}
+	}
+
+	for i, img := range images {
+		if len(img.GetFrames()) > 0 || !strings.EqualFold(path.Ext(img.GetFilename()), ".gif") {
+			continue
+		}
+
+		animation, err := decodeGIF(img.GetFilename())
+		if err != nil || len(animation.Image) <= 1 {
+			// broken files are reported by the classifier
+			continue
+		}
+
+		if dir == "" {
+			dir, err = ioutil.TempDir("", "imtag-frames")
+			if err != nil {
+				return cleanup, err
+			}
+		}
+
+		frames := []image.Frame{}
+		sampled := sampleFrames(len(animation.Image), t.maxFrames())
+		err = composeGIFFrames(animation, func(index int, frame goimage.Image) error {
+			if len(frames) == len(sampled) || sampled[len(frames)] != index {
+				return nil
+			}
+			filename := path.Join(dir, fmt.Sprintf("%d_%d.png", i, index))
+			frames = append(frames, image.Frame{Index: index, Image: image.New(filename)})
+			return writeRegion(frame, frame.Bounds(), filename)
+		})
+		if err != nil {
+			return cleanup, err
+		}
+		img.SetFrames(frames)
+	}
+
+	return cleanup, nil
+}
+
+func decodeGIF(filename string) (*gif.GIF, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return gif.DecodeAll(file)
+}
+
+// composeGIFFrames draws the frames of the animation one after another and calls f with the image
+// as it is displayed at each frame. Frames of animated GIFs often only contain the pixels which
+// changed, so they can not be classified on their own.
+func composeGIFFrames(animation *gif.GIF, f func(index int, frame goimage.Image) error) error {
+	bounds := goimage.Rect(0, 0, animation.Config.Width, animation.Config.Height)
+	if bounds.Empty() {
+		bounds = animation.Image[0].Bounds()
+	}
+	canvas := goimage.NewRGBA(bounds)
+
+	for i, frame := range animation.Image {
+		disposal := byte(0)
+		if i < len(animation.Disposal) {
+			disposal = animation.Disposal[i]
+		}
+
+		var previous *goimage.RGBA
+		if disposal == gif.DisposalPrevious {
+			previous = goimage.NewRGBA(bounds)
+			draw.Draw(previous, bounds, canvas, bounds.Min, draw.Src)
+		}
+
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		if err := f(i, canvas); err != nil {
+			return err
+		}
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), goimage.Transparent, goimage.ZP, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+	return nil
+}
+
+// classifyFrames classifies the frames of the sequences and aggregates their predictions into the
+// tags of the sequence. All predictions of the frames are needed for the aggregation, the top K are
+// taken afterwards.
+func (t *tagger) classifyFrames(sequences []image.Image) error {
+	frames := []image.Image{}
+	for _, sequence := range sequences {
+		for _, frame := range sequence.GetFrames() {
+			frames = append(frames, frame.Image)
+		}
+	}
+
+	if err := t.classifyImages(frames, 0); err != nil {
+		return err
+	}
+
+	for _, sequence := range sequences {
+		frameTags := [][]tag.Tag{}
+		for _, frame := range sequence.GetFrames() {
+			if frame.Image.GetError() == nil {
+				frameTags = append(frameTags, frame.Image.GetTags())
+			}
+		}
+
+		if len(frameTags) == 0 {
+			sequence.SetError(errors.New("no frame of the sequence could be classified"))
+			continue
+		}
+
+		tags := aggregateTags(frameTags, t.conf.SequenceMerge)
+		if t.conf.K > 0 {
+			tags = imageClassifier.TopKTags(tags, t.conf.K)
+			// the frames are tagged and shown like single images, which only have the top K tags
+			for _, frame := range sequence.GetFrames() {
+				if frame.Image.GetError() == nil {
+					frame.Image.SetTags(imageClassifier.TopKTags(frame.Image.GetTags(), t.conf.K))
+				}
+			}
+		}
+		sequence.SetTags(tags)
+	}
+	return nil
+}
+
+// aggregateTags combines the tags of several frames into one tag per label. With mean a label
+// missing in a frame counts as 0 for that frame.
+func aggregateTags(tagLists [][]tag.Tag, merge string) []tag.Tag {
+	keys := []string{}
+	first := map[string]tag.Tag{}
+	values := map[string]float32{}
+	for _, tags := range tagLists {
+		for _, t := range tags {
+			key := tagKey(t)
+			if _, ok := first[key]; !ok {
+				keys = append(keys, key)
+				first[key] = t
+			}
+
+			if merge == SequenceMergeMax {
+				if t.GetConfidence() > values[key] {
+					values[key] = t.GetConfidence()
+				}
+			} else {
+				values[key] += t.GetConfidence()
+			}
+		}
+	}
+
+	aggregated := make([]tag.Tag, 0, len(keys))
+	for _, key := range keys {
+		value := values[key]
+		if merge != SequenceMergeMax {
+			value /= float32(len(tagLists))
+		}
+		aggregated = append(aggregated, tag.NewWithSynset(first[key].GetLabel(), first[key].GetSynsetId(), value))
+	}
+	return aggregated
+}
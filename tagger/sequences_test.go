@@ -0,0 +1,129 @@
+package tagger
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/twatzl/imtag/tagger/image"
+	"github.com/twatzl/imtag/tagger/imageClassifier"
+	"github.com/twatzl/imtag/tagger/tag"
+	goimage "image"
+	"image/color"
+	"image/gif"
+	"reflect"
+	"testing"
+)
+
+func TestSampleFrames(t *testing.T) {
+	if indices := sampleFrames(3, 8); !reflect.DeepEqual(indices, []int{0, 1, 2}) {
+		t.Errorf("expected all frames of a short sequence but got %v", indices)
+	}
+	if indices := sampleFrames(10, 4); !reflect.DeepEqual(indices, []int{0, 3, 6, 9}) {
+		t.Errorf("expected evenly spread frames but got %v", indices)
+	}
+	if indices := sampleFrames(10, 1); !reflect.DeepEqual(indices, []int{5}) {
+		t.Errorf("expected the middle frame but got %v", indices)
+	}
+}
+
+func TestAggregateTags(t *testing.T) {
+	frames := [][]tag.Tag{
+		{tag.New("dog", 0.8), tag.New("cat", 0.2)},
+		{tag.New("dog", 0.4)},
+	}
+
+	mean := aggregateTags(frames, SequenceMergeMean)
+	if mean[0].GetLabel() != "dog" || mean[0].GetConfidence() != 0.6 {
+		t.Errorf("expected dog with 0.6 but got %s with %f", mean[0].GetLabel(), mean[0].GetConfidence())
+	}
+	if mean[1].GetLabel() != "cat" || mean[1].GetConfidence() != 0.1 {
+		t.Errorf("expected cat with 0.1 but got %s with %f", mean[1].GetLabel(), mean[1].GetConfidence())
+	}
+
+	max := aggregateTags(frames, SequenceMergeMax)
+	if max[0].GetConfidence() != 0.8 || max[1].GetConfidence() != 0.2 {
+		t.Errorf("expected the highest confidences but got %f and %f", max[0].GetConfidence(), max[1].GetConfidence())
+	}
+}
+
+func TestComposeGIFFrames(t *testing.T) {
+	palette := color.Palette{color.Black, color.White}
+	background := goimage.NewPaletted(goimage.Rect(0, 0, 4, 4), palette)
+	// the second frame only contains the changed pixel
+	delta := goimage.NewPaletted(goimage.Rect(2, 2, 3, 3), palette)
+	delta.SetColorIndex(2, 2, 1)
+
+	animation := &gif.GIF{
+		Image:    []*goimage.Paletted{background, delta},
+		Disposal: []byte{gif.DisposalNone, gif.DisposalNone},
+		Config:   goimage.Config{Width: 4, Height: 4},
+	}
+
+	var last goimage.Image
+	err := composeGIFFrames(animation, func(index int, frame goimage.Image) error {
+		last = frame
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if last.Bounds() != background.Bounds() {
+		t.Fatalf("expected frame of size %v but got %v", background.Bounds(), last.Bounds())
+	}
+	if r, _, _, _ := last.At(2, 2).RGBA(); r != 0xffff {
+		t.Errorf("expected the changed pixel to be white")
+	}
+	if r, _, _, a := last.At(0, 0).RGBA(); r != 0 || a != 0xffff {
+		t.Errorf("expected the pixels of the first frame to be kept")
+	}
+}
+
+// predictionsClassifier returns the predictions stored for the file name of each image
+type predictionsClassifier map[string][]tag.Tag
+
+func (c predictionsClassifier) ClassifyImages(images []image.Image) ([][]tag.Tag, error) {
+	tags := make([][]tag.Tag, len(images))
+	for i, img := range images {
+		tags[i] = c[img.GetFilename()]
+	}
+	return tags, nil
+}
+
+func (c predictionsClassifier) ClassifyImagesTopK(images []image.Image, k int) ([][]tag.Tag, error) {
+	tags, _ := c.ClassifyImages(images)
+	for i := range tags {
+		tags[i] = imageClassifier.TopKTags(tags[i], k)
+	}
+	return tags, nil
+}
+
+// labels ranked below K in a single frame must still count for the mean of the sequence
+func TestTagger_classifyFrames(t *testing.T) {
+	tr := &tagger{
+		logger: logrus.New(),
+		conf: TaggerConfig{
+			K:             1,
+			SequenceMerge: SequenceMergeMean,
+			ImageClassifier: predictionsClassifier{
+				"0.png": {tag.New("cat", 0.5), tag.New("dog", 0.4), tag.New("car", 0.1)},
+				"1.png": {tag.New("car", 0.6), tag.New("dog", 0.4)},
+			},
+		},
+	}
+
+	sequence := image.New("burst")
+	sequence.SetFrames([]image.Frame{{Index: 0, Image: image.New("0.png")}, {Index: 1, Image: image.New("1.png")}})
+
+	if err := tr.classifyFrames([]image.Image{sequence}); err != nil {
+		t.Fatal(err)
+	}
+
+	tags := sequence.GetTags()
+	if len(tags) != 1 || tags[0].GetLabel() != "dog" {
+		t.Errorf("expected dog as top tag of the sequence but got %v", tags)
+	}
+	for _, frame := range sequence.GetFrames() {
+		if len(frame.Image.GetTags()) != 1 {
+			t.Errorf("expected only the top tag for frame %d but got %v", frame.Index, frame.Image.GetTags())
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package tagger
+
+import (
+	"context"
+	"github.com/twatzl/imtag/tagger/image"
+	"time"
+)
+
+// defaultBatchSize is the number of images of a source which are tagged together if none is configured.
+const defaultBatchSize = 16
+
+// batchDelay is how long a batch waits for more images after its first image arrived. Sources which
+// send images one at a time, e.g. in a daemon, get their results without waiting for a full batch.
+const batchDelay = 100 * time.Millisecond
+
+// Result is the outcome of tagging a single image. If Err is set the image could not be tagged,
+// otherwise the tags are stored in the image.
+type Result struct {
+	Image image.Image
+	Err   error
+}
+
+// ImageSource returns a source for TagImages which sends the given images. It stops when the
+// context is canceled.
+func ImageSource(ctx context.Context, images []image.Image) <-chan image.Image {
+	source := make(chan image.Image)
+	go func() {
+		defer close(source)
+		for _, img := range images {
+			select {
+			case source <- img:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return source
+}
+
+// TagImages reads images from the source until it is closed and tags them in batches of the
+// configured size. A batch is tagged early if no more images arrive shortly after its first one.
+// Unlike LoadAndTagImages an error only affects the images of its batch, which get the error as
+// result, and the tagging continues with the next batch.
+//
+// The results are sent in the order of the source. The returned channel is closed when all images
+// are tagged or the context is canceled. The classifiers can not be interrupted, so a batch which is
+// classified when the context is canceled is still finished, but its results may be dropped. An
+// error is returned if the tagger is not configured correctly.
+func (t *tagger) TagImages(ctx context.Context, source <-chan image.Image) (<-chan Result, error) {
+	embeddedLabels, err := t.loadEmbeddedLabels()
+	if err != nil {
+		return nil, err
+	}
+
+	batchSize := t.conf.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+
+	results := make(chan Result)
+	go func() {
+		defer close(results)
+		for {
+			batch, more := nextBatch(ctx, source, batchSize, batchDelay)
+			if len(batch) > 0 && ctx.Err() == nil {
+				if !t.sendResults(ctx, results, batch, t.tagBatch(embeddedLabels, batch)) {
+					return
+				}
+			}
+			if !more || ctx.Err() != nil {
+				return
+			}
+		}
+	}()
+	return results, nil
+}
+
+// nextBatch collects up to size images from the source. It waits for the first image as long as
+// needed, but returns the batch at most delay after the first image even if it is not full. more
+// is false if the source is closed or the context is canceled.
+func nextBatch(ctx context.Context, source <-chan image.Image, size int, delay time.Duration) (batch []image.Image, more bool) {
+	batch = make([]image.Image, 0, size)
+	// the timeout is nil and therefore blocks until the first image arrived
+	var timeout <-chan time.Time
+	for len(batch) < size {
+		select {
+		case img, ok := <-source:
+			if !ok {
+				return batch, false
+			}
+			batch = append(batch, img)
+			if timeout == nil {
+				timer := time.NewTimer(delay)
+				defer timer.Stop()
+				timeout = timer.C
+			}
+		case <-timeout:
+			return batch, true
+		case <-ctx.Done():
+			return batch, false
+		}
+	}
+	return batch, true
+}
+
+// sendResults sends the result of each image of the batch. If the batch failed as a whole all of
+// its images get the error. It returns false if the context was canceled.
+func (t *tagger) sendResults(ctx context.Context, results chan<- Result, batch []image.Image, batchErr error) bool {
+	for _, img := range batch {
+		if batchErr != nil {
+			img.SetError(batchErr)
+		}
+
+		select {
+		case results <- Result{Image: img, Err: img.GetError()}:
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,165 @@
+package tagger
+
+import (
+	"context"
+	"errors"
+	"github.com/fluhus/gostuff/nlp/wordnet"
+	"github.com/sirupsen/logrus"
+	"github.com/twatzl/imtag/tagger/image"
+	"github.com/twatzl/imtag/tagger/tag"
+	"testing"
+	"time"
+)
+
+type testWord2Vec struct {
+	vectors map[string][]float32
+}
+
+func (w *testWord2Vec) Word2Vec(word string) []float32 { return w.vectors[word] }
+func (w *testWord2Vec) Word2VecBatch(words []string) [][]float32 {
+	result := make([][]float32, len(words))
+	for i, word := range words {
+		result[i] = w.vectors[word]
+	}
+	return result
+}
+func (w *testWord2Vec) NormalizedWord2Vec(word string) []float32 { return w.vectors[word] }
+func (w *testWord2Vec) Contains(word string) bool {
+	_, ok := w.vectors[word]
+	return ok
+}
+func (w *testWord2Vec) GetDim() int          { return 2 }
+func (w *testWord2Vec) Vocabulary() []string { return nil }
+func (w *testWord2Vec) ForEachWord(fn func(word string, vector []float32) error) error {
+	return nil
+}
+
+// testLabelStorage holds the synset ids of animal and vehicle, like labels added by AddNewLabel
+type testLabelStorage struct{}
+
+func (s testLabelStorage) StoreLabels(labels map[string]interface{}) error { return nil }
+func (s testLabelStorage) LoadLabelsMap() (map[string]interface{}, error) {
+	return map[string]interface{}{"n00015388": "", "n04524313": ""}, nil
+}
+func (s testLabelStorage) LoadLabelsSlice() ([]string, error) {
+	return []string{"n00015388", "n04524313"}, nil
+}
+
+// testClassifier fails for every batch which contains the image "broken"
+type testClassifier struct{}
+
+func (c testClassifier) ClassifyImages(images []image.Image) ([][]tag.Tag, error) {
+	return c.ClassifyImagesTopK(images, 0)
+}
+
+func (c testClassifier) ClassifyImagesTopK(images []image.Image, k int) ([][]tag.Tag, error) {
+	tags := make([][]tag.Tag, len(images))
+	for i, img := range images {
+		if img.GetFilename() == "broken" {
+			return nil, errors.New("classifier failed")
+		}
+		tags[i] = []tag.Tag{tag.New("dog", 1)}
+	}
+	return tags, nil
+}
+
+func newTestTagger() *tagger {
+	return &tagger{
+		logger: logrus.New(),
+		conf: TaggerConfig{
+			K:               1,
+			BatchSize:       2,
+			ImageClassifier: testClassifier{},
+			LabelStorage:    testLabelStorage{},
+			WordNet: &wordnet.WordNet{Synset: map[string]*wordnet.Synset{
+				"n00015388": {Offset: "00015388", Pos: "n", Word: []string{"animal", "animate_being"}},
+				"n04524313": {Offset: "04524313", Pos: "n", Word: []string{"vehicle"}},
+			}},
+			Word2VecModel: &testWord2Vec{vectors: map[string][]float32{
+				"dog": {1, 0}, "animal": {0.9, 0.1}, "vehicle": {0, 1},
+			}},
+		},
+	}
+}
+
+func TestTagger_TagImages(t *testing.T) {
+	images := []image.Image{image.New("a"), image.New("b"), image.New("broken"), image.New("c"), image.New("d")}
+
+	// all images are available at once, so the batches do not depend on the timing
+	source := make(chan image.Image, len(images))
+	for _, img := range images {
+		source <- img
+	}
+	close(source)
+
+	results, err := newTestTagger().TagImages(context.Background(), source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	received := []Result{}
+	for result := range results {
+		received = append(received, result)
+	}
+
+	if len(received) != len(images) {
+		t.Fatalf("expected %d results but got %d", len(images), len(received))
+	}
+	for i, result := range received {
+		if result.Image != images[i] {
+			t.Errorf("expected result %d to be for image %s", i, images[i].GetFilename())
+		}
+		// the failed batch holds the images broken and c
+		failed := i == 2 || i == 3
+		if failed != (result.Err != nil) {
+			t.Errorf("unexpected error %v for image %s", result.Err, result.Image.GetFilename())
+		}
+		if failed {
+			continue
+		}
+		tags := result.Image.GetTags()
+		if len(tags) != 1 || tags[0].GetSynsetId() != "n00015388" || tags[0].GetLabel() != "animal" {
+			t.Errorf("expected image %s to be tagged with animal (n00015388) but got %v", result.Image.GetFilename(), tags)
+		}
+	}
+}
+
+func TestTagger_TagImages_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	source := make(chan image.Image)
+
+	results, err := newTestTagger().TagImages(ctx, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the source is never closed, so the results are only closed because of the cancellation
+	source <- image.New("a")
+	cancel()
+	for range results {
+	}
+}
+
+// a source which sends images one at a time and stays open must not wait for a full batch
+func TestTagger_TagImages_OpenSource(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	source := make(chan image.Image)
+
+	results, err := newTestTagger().TagImages(ctx, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"a", "b"} {
+		source <- image.New(name)
+		select {
+		case result := <-results:
+			if result.Image.GetFilename() != name || result.Err != nil {
+				t.Errorf("unexpected result for %s: %s %v", name, result.Image.GetFilename(), result.Err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no result for %s while the source is open", name)
+		}
+	}
+}
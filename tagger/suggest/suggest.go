@@ -0,0 +1,101 @@
+// The package suggest provides spelling suggestions for words which are not found in a vocabulary.
+package suggest
+
+import (
+	"sort"
+	"strings"
+)
+
+type suggestion struct {
+	word     string
+	distance int
+}
+
+/**
+ * Suggest returns up to n words from the vocabulary which have an edit distance of at most
+ * maxDistance to the given word. The suggestions are sorted by distance. The comparison
+ * is case insensitive.
+ */
+func Suggest(word string, vocabulary []string, n int, maxDistance int) []string {
+	word = strings.ToLower(word)
+	seen := map[string]bool{}
+	suggestions := []suggestion{}
+
+	for _, candidate := range vocabulary {
+		lower := strings.ToLower(candidate)
+		if seen[lower] {
+			continue
+		}
+		seen[lower] = true
+
+		// the distance is at least the difference in length, so we can skip most words early
+		if abs(len(lower)-len(word)) > maxDistance {
+			continue
+		}
+
+		distance := Levenshtein(word, lower)
+		if distance <= maxDistance {
+			suggestions = append(suggestions, suggestion{candidate, distance})
+		}
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].distance != suggestions[j].distance {
+			return suggestions[i].distance < suggestions[j].distance
+		}
+		return suggestions[i].word < suggestions[j].word
+	})
+
+	if len(suggestions) > n {
+		suggestions = suggestions[:n]
+	}
+
+	result := make([]string, len(suggestions))
+	for i, s := range suggestions {
+		result[i] = s.word
+	}
+	return result
+}
+
+/**
+ * Levenshtein computes the minimum number of single character insertions, deletions and
+ * substitutions needed to change a into b.
+ */
+func Levenshtein(a, b string) int {
+	ra := []rune(a)
+	rb := []rune(b)
+
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(min(previous[j]+1, current[j-1]+1), previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(rb)]
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func abs(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
@@ -0,0 +1,40 @@
+package suggest
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"dog", "dog", 0},
+		{"dog", "dig", 1},
+		{"kitten", "sitting", 3},
+		{"", "cat", 3},
+		{"giraffe", "girafe", 1},
+	}
+	for _, tt := range tests {
+		if got := Levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("Levenshtein(%s, %s) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	vocabulary := []string{"giraffe", "Giraffe", "gaffe", "giraffes", "graph", "dog", "griffin"}
+
+	got := Suggest("girafe", vocabulary, 2, 2)
+	want := []string{"giraffe", "giraffes"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Suggest() = %v, want %v", got, want)
+	}
+
+	got = Suggest("xyz", vocabulary, 5, 1)
+	if len(got) != 0 {
+		t.Errorf("Suggest() = %v, want no suggestions", got)
+	}
+}
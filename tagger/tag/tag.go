@@ -2,11 +2,15 @@ package tag
 
 type Tag interface {
 	GetLabel() string
+	// GetSynsetId returns the WordNet id of the label or an empty string if the label is not
+	// mapped to a synset.
+	GetSynsetId() string
 	GetConfidence() float32
 }
 
 type tag struct {
 	label string
+	synsetId string
 	confidence float32
 }
 
@@ -14,6 +18,10 @@ func (t *tag) GetLabel() string {
 	return t.label
 }
 
+func (t *tag) GetSynsetId() string {
+	return t.synsetId
+}
+
 func (t *tag) GetConfidence() float32 {
 	return t.confidence
 }
@@ -24,3 +32,13 @@ func New(label string, confidence float32) Tag {
 		confidence: confidence,
 	}
 }
+
+// NewWithSynset creates a tag whose label is mapped to a WordNet synset. The label is only used
+// for display, lookups are done with the synset id.
+func NewWithSynset(label string, synsetId string, confidence float32) Tag {
+	return &tag{
+		label: label,
+		synsetId: synsetId,
+		confidence: confidence,
+	}
+}
@@ -1,6 +1,7 @@
 package tagger
 
 import (
+	"context"
 	"fmt"
 	"github.com/fluhus/gostuff/nlp/wordnet"
 	"github.com/mewkiz/pkg/osutil"
@@ -10,8 +11,11 @@ import (
 	"github.com/twatzl/imtag/tagger/knn"
 	"github.com/twatzl/imtag/tagger/label"
 	"github.com/twatzl/imtag/tagger/tag"
+	"io/ioutil"
 	"os"
+	"path"
 	"regexp"
+	"strings"
 )
 
 type TensorFlowModelType int;
@@ -25,6 +29,11 @@ type Tagger interface {
 	 */
 	AddNewLabel(label string) error
 	LoadAndTagImages(imagePath string) ([]image.Image, error)
+	// LoadImages returns the image or the images of the directory at the given path, see TagImages.
+	LoadImages(imagePath string) ([]image.Image, error)
+	// TagImages tags the images received from the source in batches and sends a result for each
+	// image as soon as its batch is done. See the Result type.
+	TagImages(ctx context.Context, source <-chan image.Image) (<-chan Result, error)
 }
 
 type tagger struct {
@@ -54,7 +63,7 @@ func (t *tagger) AddNewLabel(label string) error {
 	if matched {
 		synset, ok := t.conf.WordNet.Synset[label]
 		if !ok {
-			message := "synset not found. are you using the correct WordNet version?"
+			message := "synset not found. are you using the correct WordNet version? (ids can be translated with migrateLabels)"
 			t.logger.WithField("synsetid", label).Error(message)
 			return errors.New(message)
 		}
@@ -94,79 +103,215 @@ func (t *tagger) AddNewLabel(label string) error {
 }
 
 func (t *tagger) LoadAndTagImages(imagePath string) (result []image.Image, err error) {
-	if t.conf.LabelStorage == nil {
-		err = errors.New("no label storage module defined")
+	embeddedLabels, err := t.loadEmbeddedLabels()
+	if err != nil {
 		return nil, err
 	}
 
-	if t.conf.Word2VecModel == nil {
-		err = errors.New("no word2vec model loaded")
+	images, err := t.LoadImages(imagePath)
+	if err != nil {
 		return nil, err
 	}
 
+	err = t.tagBatch(embeddedLabels, images)
+	if err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+// loadEmbeddedLabels checks that everything needed for tagging is configured and embeds the labels
+// of the label storage.
+func (t *tagger) loadEmbeddedLabels() ([]label.Label, error) {
+	if t.conf.LabelStorage == nil {
+		return nil, errors.New("no label storage module defined")
+	}
+
+	if t.conf.Word2VecModel == nil {
+		return nil, errors.New("no word2vec model loaded")
+	}
+
+	if t.conf.ImageClassifier == nil {
+		return nil, errors.New("no classifier loaded")
+	}
+
 	labels, err := t.conf.LabelStorage.LoadLabelsSlice()
 	if err != nil {
 		return nil, err
 	}
-	embeddedLabels := t.embedKnownLabels(labels)
+	return t.embedKnownLabels(labels), nil
+}
 
-	images, err := t.loadAndClassifyImages(imagePath)
+// tagBatch classifies the images and replaces the tags of the classifier by the zero-shot tags.
+// Images which could not be loaded or classified get an error, the others are still tagged.
+func (t *tagger) tagBatch(embeddedLabels []label.Label, images []image.Image) error {
+	err := t.classifyBatch(images)
 	if err != nil {
 		t.logger.WithError(err).Errorln("error during loading and classification of images")
-		return nil, err
+		return err
+	}
+
+	// images which could not be classified are skipped
+	classified := withoutErrors(images)
+
+	var tiled []tiledImage
+	if t.conf.Tiles > 0 {
+		var cleanup func()
+		tiled, cleanup, err = t.classifyRegions(withoutSequences(classified))
+		if err != nil {
+			t.logger.WithError(err).Errorln("error during classification of image regions")
+			return err
+		}
+		defer cleanup()
 	}
 
 	if t.conf.RawClassifierResults {
-		return images, nil
+		for _, ti := range tiled {
+			ti.setRegions(t.conf.K)
+		}
+		return nil
 	}
 
-	vec := embedImageFlat(t.conf.Word2VecModel, images[0])
+	// the regions and frames are tagged the same way as the images, the tags of the regions are
+	// merged afterwards
+	targets := classified
+	for _, ti := range tiled {
+		targets = append(targets, withoutErrors(ti.regions)...)
+	}
+	for _, img := range classified {
+		for _, frame := range img.GetFrames() {
+			if frame.Image.GetError() == nil {
+				targets = append(targets, frame.Image)
+			}
+		}
+	}
+	t.zeroShotTag(embeddedLabels, targets)
 
-	resultLabels := knn.KnnSearch(embeddedLabels, [][]float32{vec}, t.conf.K, knn.CosDist)[0]
+	for _, ti := range tiled {
+		ti.setRegions(t.conf.K)
+	}
 
-	tags := make([]tag.Tag, len(resultLabels))
-	for i := range resultLabels {
-		// TODO fix confidence
-		tags[i] = tag.New(resultLabels[i].GetLabel(), 0)
+	return nil
+}
+
+// zeroShotTag replaces the tags of the classifier by the known labels nearest to the embedding of
+// the images. The confidence of a tag is the cosine similarity of the label and the image.
+func (t *tagger) zeroShotTag(embeddedLabels []label.Label, images []image.Image) {
+	vectors := make([][]float32, len(images))
+	for i, img := range images {
+		vectors[i] = embedImageFlat(t.conf.Word2VecModel, t.conf.WordNet, img)
 	}
-	images[0].SetTags(tags)
 
-	return
+	neighbors := knn.KnnSearchWithDistances(embeddedLabels, vectors, t.conf.K, knn.CosDist)
+
+	for i, img := range images {
+		tags := make([]tag.Tag, len(neighbors[i]))
+		for j, n := range neighbors[i] {
+			tags[j] = t.labelTag(n.Label.GetLabel(), 1-n.Distance)
+		}
+		img.SetTags(tags)
+	}
+}
+
+// labelTag creates the tag for a known label. Labels which are synset ids are named by the first
+// word of the synset.
+func (t *tagger) labelTag(l string, confidence float32) tag.Tag {
+	if synset := t.synset(l); synset != nil && len(synset.Word) > 0 {
+		return tag.NewWithSynset(strings.Replace(synset.Word[0], "_", " ", -1), l, confidence)
+	}
+	return tag.New(l, confidence)
+}
+
+// withoutSequences returns the images which consist of a single frame.
+func withoutSequences(images []image.Image) []image.Image {
+	result := make([]image.Image, 0, len(images))
+	for _, img := range images {
+		if len(img.GetFrames()) == 0 {
+			result = append(result, img)
+		}
+	}
+	return result
+}
+
+// withoutErrors returns the images which were processed without error.
+func withoutErrors(images []image.Image) []image.Image {
+	result := make([]image.Image, 0, len(images))
+	for _, img := range images {
+		if img.GetError() == nil {
+			result = append(result, img)
+		}
+	}
+	return result
 }
 
-func (t *tagger) loadAndClassifyImages(imagePath string) (result []image.Image, err error) {
+func (t *tagger) LoadImages(imagePath string) ([]image.Image, error) {
+	return t.prepareImageBatch(imagePath)
+}
+
+// classifyBatch classifies the images and the frames of the sequences among them.
+func (t *tagger) classifyBatch(images []image.Image) error {
 	if t.conf.ImageClassifier == nil {
-		err = errors.New("no classifier loaded")
-		return nil, err
+		return errors.New("no classifier loaded")
 	}
 
-	images, err := t.prepareImageBatch(imagePath)
+	// the frames are only needed for the classification
+	cleanup, err := t.splitAnimatedGIFs(images)
+	defer cleanup()
 	if err != nil {
-		return nil, err
+		t.logger.WithError(err).Errorln("could not split animated images into frames")
+		return err
 	}
 
+	sequences := []image.Image{}
+	for _, img := range images {
+		if len(img.GetFrames()) > 0 {
+			sequences = append(sequences, img)
+		}
+	}
+
+	singles := withoutSequences(images)
+	if len(singles) > 0 || len(sequences) == 0 {
+		if err := t.classifyImages(singles, t.conf.K); err != nil {
+			return err
+		}
+	}
+	if len(sequences) > 0 {
+		if err := t.classifyFrames(sequences); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// classifyImages classifies the images and stores the top k tags of the classifier in them, all
+// tags if k is 0.
+func (t *tagger) classifyImages(images []image.Image, k int) (err error) {
 	var tags [][]tag.Tag
-	if t.conf.K == 0 {
+	if k == 0 {
 		tags, err = t.conf.ImageClassifier.ClassifyImages(images)
 	} else {
-		tags, err = t.conf.ImageClassifier.ClassifyImagesTopK(images, t.conf.K)
+		tags, err = t.conf.ImageClassifier.ClassifyImagesTopK(images, k)
 	}
 
 	if err != nil {
 		err = errors.New("error during image classification")
-		return nil, err
+		return err
 	}
 
 	if tags == nil {
 		err = errors.New("got no tags for image")
-		return nil, err
+		return err
 	}
 
 	for i, img := range images {
+		if err := img.GetError(); err != nil {
+			t.logger.WithField("file", img.GetFilename()).WithError(err).Warnln("skipping image")
+			continue
+		}
 		img.SetTags(tags[i])
 	}
 
-	return images, nil
+	return nil
 }
 
 func (t *tagger) prepareImageBatch(imagePath string) (imageBatch []image.Image, err error) {
@@ -187,8 +332,19 @@ func (t *tagger) prepareImageBatch(imagePath string) (imageBatch []image.Image,
 	}
 
 	var images []image.Image = nil
-	if fi.Mode().IsDir() {
-		images = t.imageBatch(imagePath)
+	if fi.Mode().IsDir() && isSequenceDir(imagePath) {
+		sequence, err := t.sequenceFromDir(imagePath)
+		if err != nil {
+			t.logger.WithField("imagePath", imagePath).WithError(err).Errorln("could not read sequence")
+			return nil, err
+		}
+		images = []image.Image{sequence}
+	} else if fi.Mode().IsDir() {
+		images, err = t.imageBatch(imagePath)
+		if err != nil {
+			t.logger.WithField("imagePath", imagePath).WithError(err).Errorln("could not read directory")
+			return nil, err
+		}
 	} else if fi.Mode().IsRegular() {
 		img := t.singleImage(imagePath)
 		images = []image.Image{img}
@@ -206,23 +362,91 @@ func (t *tagger) singleImage(filename string) image.Image {
 	return image.New(filename)
 }
 
-func (t *tagger) imageBatch(s string) []image.Image {
-	// TODO: implement
+// imageBatch returns all files in the given directory as images. Subdirectories which are marked
+// as sequences are tagged as one image each, hidden files and other subdirectories are skipped.
+func (t *tagger) imageBatch(dir string) ([]image.Image, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
 
-	return nil
+	images := []image.Image{}
+	for _, f := range files {
+		if strings.HasPrefix(f.Name(), ".") {
+			continue
+		}
+
+		filename := path.Join(dir, f.Name())
+		if f.IsDir() && isSequenceDir(filename) {
+			sequence, err := t.sequenceFromDir(filename)
+			if err != nil {
+				return nil, err
+			}
+			images = append(images, sequence)
+		} else if f.Mode().IsRegular() {
+			images = append(images, t.singleImage(filename))
+		}
+	}
+
+	return images, nil
+}
+
+// imageFiles returns the names of the files in the directory ordered by name. Hidden files like
+// the sequence marker are skipped.
+func imageFiles(dir string) ([]string, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	filenames := []string{}
+	for _, f := range files {
+		if f.Mode().IsRegular() && !strings.HasPrefix(f.Name(), ".") {
+			filenames = append(filenames, path.Join(dir, f.Name()))
+		}
+	}
+	return filenames, nil
 }
 
+// synsetIdPattern matches the WordNet synset ids which are stored as labels by AddNewLabel.
+var synsetIdPattern = regexp.MustCompile("^n[0-9]{8}$")
+
 /**
  * embedKnownLabels embeds the labels which were choosen by the user before in our
  * n-dimensional vector space. This is done on demand so that the user can change the
  * implementation of word2vec without the need to re register all data again.
+ * Labels are stored as synset ids and embedded by the words of the synset, other labels are
+ * looked up as words. Labels which can not be embedded are skipped.
  */
 func (t *tagger) embedKnownLabels(labels []string) (embeddedLabels []label.Label) {
 
 	for _, l := range labels {
-		vector := t.conf.Word2VecModel.Word2Vec(l)
+		var vector []float32
+		if synsetIdPattern.MatchString(l) {
+			synset := t.synset(l)
+			if synset == nil {
+				t.logger.WithField("label", l).Warnln("skipping label, synset not found in WordNet")
+				continue
+			}
+			vector = EmbedSynset(t.conf.Word2VecModel, synset)
+		} else {
+			vector = t.conf.Word2VecModel.Word2Vec(l)
+		}
+
+		if vector == nil {
+			t.logger.WithField("label", l).Warnln("skipping label, it is not known to word2vec")
+			continue
+		}
 		embeddedLabels = append(embeddedLabels, label.New(l, vector))
 	}
 
 	return embeddedLabels
 }
+
+// synset returns the synset with the given id or nil if it is not part of the dictionary.
+func (t *tagger) synset(id string) *wordnet.Synset {
+	if t.conf.WordNet == nil {
+		return nil
+	}
+	return t.conf.WordNet.Synset[id]
+}
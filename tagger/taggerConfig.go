@@ -15,4 +15,14 @@ type TaggerConfig struct {
 	LabelStorage         LabelStorage
 	EmbedHierarchical    bool
 	RawClassifierResults bool
+	// Tiles is the number of rows and columns of the grid of regions which are tagged in addition
+	// to the whole image, 0 disables the tiling. Neighbouring regions overlap by TileOverlap.
+	Tiles       int
+	TileOverlap float64
+	// MaxFrames is the number of frames sampled from animated GIFs and sequence directories (default
+	// 8). Their predictions are aggregated with SequenceMerge (mean or max, default mean).
+	MaxFrames     int
+	SequenceMerge string
+	// BatchSize is the number of images of a source which are tagged together by TagImages (default 16)
+	BatchSize int
 }
@@ -1,13 +1,16 @@
 package skipGramModel
 
 import (
+	"bufio"
 	"github.com/twatzl/imtag/tagger/word2vec"
 	"encoding/binary"
+	"io"
 	"io/ioutil"
 	"log"
 	"math"
 	"os"
 	"path"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -27,16 +30,101 @@ type skipGramModel struct {
 	idFilePath    string
 	dataFilePath  string
 	name2Index    map[string]int
+	// normalizedName2Index is used if there is no exact match for a word
+	normalizedName2Index map[string]int
 }
 
 func (w *skipGramModel) Word2Vec(word string) []float32 {
 	return w.read(word)
 }
 
+func (w *skipGramModel) Word2VecBatch(words []string) [][]float32 {
+	const FLOAT32_SIZE = 4
+	result := make([][]float32, len(words))
+
+	// read in order of the data file to avoid seeking back and forth
+	order := []int{}
+	indices := make([]int, len(words))
+	for i, word := range words {
+		wordIndex, ok := w.index(word)
+		if ok {
+			indices[i] = wordIndex
+			order = append(order, i)
+		}
+	}
+
+	if len(order) == 0 {
+		return result
+	}
+
+	sort.Slice(order, func(i, j int) bool {
+		return indices[order[i]] < indices[order[j]]
+	})
+
+	f, err := os.Open(w.dataFilePath)
+	check(err)
+	defer f.Close()
+
+	rawdata := make([]byte, w.numDims*FLOAT32_SIZE)
+	for _, i := range order {
+		offset := w.numDims * int64(indices[i]) * FLOAT32_SIZE
+		_, err = f.ReadAt(rawdata, offset)
+		check(err)
+		result[i] = w.decode(rawdata)
+	}
+
+	return result
+}
+
+func (w *skipGramModel) NormalizedWord2Vec(word string) []float32 {
+	return word2vec.Normalize(w.read(word))
+}
+
+func (w *skipGramModel) Contains(word string) bool {
+	_, ok := w.index(word)
+	return ok
+}
+
 func (w *skipGramModel) GetDim() int {
 	return int(w.numDims)
 }
 
+func (w *skipGramModel) Vocabulary() []string {
+	n := w.numWords
+	if len(w.names) < n {
+		n = len(w.names)
+	}
+	return w.names[:n]
+}
+
+// ForEachWord reads the data file sequentially, which is a lot faster than
+// looking up every word on its own.
+func (w *skipGramModel) ForEachWord(fn func(word string, vector []float32) error) error {
+	const FLOAT32_SIZE = 4
+	f, err := os.Open(w.dataFilePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	rawdata := make([]byte, w.numDims*FLOAT32_SIZE)
+
+	for _, word := range w.Vocabulary() {
+		_, err := io.ReadFull(reader, rawdata)
+		if err != nil {
+			return err
+		}
+
+		err = fn(word, w.decode(rawdata))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func New(basePath string) word2vec.Word2Vec {
 	w := &skipGramModel{}
 	w.init(basePath)
@@ -68,11 +156,27 @@ func (w *skipGramModel) init(basePath string) {
 
 	// indexing
 	w.name2Index = make(map[string]int, len(w.names))
+	w.normalizedName2Index = make(map[string]int, len(w.names))
 	for i, word := range w.names {
 		w.name2Index[word] = i
+
+		normalized := word2vec.NormalizeWord(word)
+		if _, exists := w.normalizedName2Index[normalized]; !exists {
+			w.normalizedName2Index[normalized] = i
+		}
 	}
 }
 
+// index returns the position of the word in the data file. Exact matches are preferred over
+// matches of the normalized word.
+func (w *skipGramModel) index(word string) (int, bool) {
+	wordIndex, ok := w.name2Index[word]
+	if !ok {
+		wordIndex, ok = w.normalizedName2Index[word2vec.NormalizeWord(word)]
+	}
+	return wordIndex, ok
+}
+
 func check(err error) {
 	if err != nil {
 		log.Fatal(err)
@@ -81,7 +185,7 @@ func check(err error) {
 
 func (w *skipGramModel) read(word string) []float32 {
 	const FLOAT32_SIZE = 4
-	wordIndex, ok := w.name2Index[word]
+	wordIndex, ok := w.index(word)
 	if !ok {
 		// word not in w2v model
 		return nil
@@ -97,10 +201,14 @@ func (w *skipGramModel) read(word string) []float32 {
 	check(err)
 
 	rawdata := make([]byte, w.numDims*FLOAT32_SIZE)
-	n, err := f.Read(rawdata)
+	_, err = io.ReadFull(f, rawdata)
 	check(err)
-	log.Printf("%d bytes read from file\n", n)
 
+	return w.decode(rawdata)
+}
+
+func (w *skipGramModel) decode(rawdata []byte) []float32 {
+	const FLOAT32_SIZE = 4
 	data := make([]float32, w.numDims)
 
 	for i := 0; i < len(data); i++ {
@@ -1,6 +1,14 @@
 package skipGramModel
 
 import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -16,4 +24,74 @@ func Test_skipGramModel_integration(t *testing.T) {
 
 	println("dimensions: ", w.GetDim())
 	println("number of words: ", len(w.name2Index))
-}
\ No newline at end of file
+}
+
+// newTestModel writes a model with the given words and vectors of 2 dimensions into a temporary
+// directory and loads it.
+func newTestModel(t *testing.T, words []string, vectors [][]float32) (*skipGramModel, func()) {
+	dir, err := ioutil.TempDir("", "skipGramModel")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte{}
+	for _, vec := range vectors {
+		for _, v := range vec {
+			buf := make([]byte, 4)
+			binary.LittleEndian.PutUint32(buf, math.Float32bits(v))
+			data = append(data, buf...)
+		}
+	}
+
+	files := map[string][]byte{
+		shapeFile: []byte(fmt.Sprintf("%d 2\n", len(words))),
+		idFile:    []byte(strings.Join(words, " ")),
+		dataFile:  data,
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(path.Join(dir, name), content, 0644); err != nil {
+			os.RemoveAll(dir)
+			t.Fatal(err)
+		}
+	}
+
+	w := &skipGramModel{}
+	w.init(dir)
+	return w, func() { os.RemoveAll(dir) }
+}
+
+func Test_skipGramModel_Lookups(t *testing.T) {
+	w, cleanup := newTestModel(t,
+		[]string{"dog", "golden_retriever", "Golden_Retriever", "cat"},
+		[][]float32{{1, 0}, {3, 4}, {5, 5}, {0, 2}})
+	defer cleanup()
+
+	if !reflect.DeepEqual(w.Word2Vec("cat"), []float32{0, 2}) {
+		t.Errorf("Word2Vec(cat) = %v, want [0 2]", w.Word2Vec("cat"))
+	}
+	// exact matches are preferred, otherwise the first word with the same normalized form is used
+	if !reflect.DeepEqual(w.Word2Vec("Golden_Retriever"), []float32{5, 5}) {
+		t.Errorf("Word2Vec(Golden_Retriever) = %v, want [5 5]", w.Word2Vec("Golden_Retriever"))
+	}
+	if !reflect.DeepEqual(w.Word2Vec("golden retriever"), []float32{3, 4}) {
+		t.Errorf("Word2Vec(golden retriever) = %v, want [3 4]", w.Word2Vec("golden retriever"))
+	}
+	if w.Word2Vec("horse") != nil {
+		t.Errorf("Word2Vec(horse) = %v, want nil", w.Word2Vec("horse"))
+	}
+
+	if !w.Contains("Dog") || w.Contains("horse") {
+		t.Errorf("Contains() does not match the vocabulary")
+	}
+
+	if got := w.NormalizedWord2Vec("golden retriever"); !reflect.DeepEqual(got, []float32{0.6, 0.8}) {
+		t.Errorf("NormalizedWord2Vec(golden retriever) = %v, want [0.6 0.8]", got)
+	}
+
+	// the batch is read in file order but returned in the order of the words
+	got := w.Word2VecBatch([]string{"cat", "horse", "dog"})
+	want := [][]float32{{0, 2}, nil, {1, 0}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Word2VecBatch() = %v, want %v", got, want)
+	}
+}
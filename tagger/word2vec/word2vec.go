@@ -1,6 +1,55 @@
 package word2vec
 
+import (
+	"math"
+	"strings"
+)
+
 type Word2Vec interface {
+	// Word2Vec returns the vector for the given word or nil if the word is unknown.
+	// Lookups are done with the normalized word (see NormalizeWord) if there is no exact match.
 	Word2Vec(word string) []float32
+	// Word2VecBatch looks up several words at once. The result contains nil for unknown words.
+	Word2VecBatch(words []string) [][]float32
+	// NormalizedWord2Vec returns the L2 normalized vector for the given word or nil if the word is unknown.
+	NormalizedWord2Vec(word string) []float32
+	// Contains checks if the word is known to the model without reading its vector.
+	Contains(word string) bool
 	GetDim() int
+	// Vocabulary returns all words known to the model.
+	Vocabulary() []string
+	// ForEachWord calls fn for every word of the vocabulary together with its vector. The iteration
+	// stops at the first error returned by fn, which is then returned.
+	ForEachWord(fn func(word string, vector []float32) error) error
+}
+
+// NormalizeWord converts a word into the form used for lookups. ImageNet label strings use spaces
+// ("golden retriever") while WordNet lemmas use underscores and upper case ("Canis_familiaris"), so
+// words are converted to lower case with underscores instead of spaces.
+func NormalizeWord(word string) string {
+	word = strings.TrimSpace(strings.ToLower(word))
+	return strings.Join(strings.Fields(word), "_")
+}
+
+// Normalize returns a copy of the vector scaled to unit length. Vectors of length 0 are returned unchanged.
+func Normalize(vec []float32) []float32 {
+	if vec == nil {
+		return nil
+	}
+
+	var norm float64
+	for _, v := range vec {
+		norm += float64(v) * float64(v)
+	}
+	norm = math.Sqrt(norm)
+
+	normalized := make([]float32, len(vec))
+	for i, v := range vec {
+		if norm == 0 {
+			normalized[i] = v
+		} else {
+			normalized[i] = float32(float64(v) / norm)
+		}
+	}
+	return normalized
 }
@@ -0,0 +1,38 @@
+package word2vec
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNormalizeWord(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		{"dog", "dog"},
+		{"golden retriever", "golden_retriever"},
+		{"Canis_familiaris", "canis_familiaris"},
+		{" Great  White shark ", "great_white_shark"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeWord(tt.word); got != tt.want {
+			t.Errorf("NormalizeWord(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	got := Normalize([]float32{3, 4})
+	if math.Abs(float64(got[0])-0.6) > 1e-6 || math.Abs(float64(got[1])-0.8) > 1e-6 {
+		t.Errorf("Normalize() = %v, want [0.6 0.8]", got)
+	}
+
+	if got := Normalize([]float32{0, 0}); got[0] != 0 || got[1] != 0 {
+		t.Errorf("Normalize() = %v, want [0 0]", got)
+	}
+
+	if got := Normalize(nil); got != nil {
+		t.Errorf("Normalize(nil) = %v, want nil", got)
+	}
+}
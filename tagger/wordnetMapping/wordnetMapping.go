@@ -0,0 +1,180 @@
+// The package wordnetMapping translates synset ids between different versions of WordNet.
+// The offsets which are used as synset ids change between versions (e.g. 3.0 and 3.1), while
+// the sense keys of the words stay mostly the same. So a mapping can either be derived from the
+// sense indices of two dictionaries or be loaded from a mapping file.
+package wordnetMapping
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/pkg/errors"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+const senseIndexFile = "index.sense"
+
+// Mapping translates synset ids of one WordNet version into synset ids of another version.
+type Mapping interface {
+	// Map returns the synset id in the target version for a synset id of the source version.
+	// The second return value is false if the synset could not be mapped.
+	Map(synsetId string) (string, bool)
+	// Len returns the number of synset ids known to the mapping.
+	Len() int
+}
+
+type mapping struct {
+	ids map[string]string
+}
+
+func (m *mapping) Map(synsetId string) (string, bool) {
+	id, ok := m.ids[synsetId]
+	return id, ok
+}
+
+func (m *mapping) Len() int {
+	return len(m.ids)
+}
+
+// NewFromSenseIndex creates a mapping from the source to the target dictionary. Both paths must point to
+// a WordNet dict directory containing the index.sense file. Synsets are matched by the sense keys of their
+// words. If the words of a synset were split up between versions the synset is mapped to the target synset
+// which shares most of the sense keys.
+func NewFromSenseIndex(sourceDictPath, targetDictPath string) (Mapping, error) {
+	sourceIndex, err := readSenseIndex(path.Join(sourceDictPath, senseIndexFile))
+	if err != nil {
+		return nil, err
+	}
+
+	targetIndex, err := readSenseIndex(path.Join(targetDictPath, senseIndexFile))
+	if err != nil {
+		return nil, err
+	}
+
+	// count how many sense keys of each source synset point to the target synsets
+	votes := map[string]map[string]int{}
+	for senseKey, sourceId := range sourceIndex {
+		targetId, ok := targetIndex[senseKey]
+		if !ok {
+			continue
+		}
+
+		if votes[sourceId] == nil {
+			votes[sourceId] = map[string]int{}
+		}
+		votes[sourceId][targetId]++
+	}
+
+	ids := make(map[string]string, len(votes))
+	for sourceId, candidates := range votes {
+		ids[sourceId] = bestCandidate(candidates)
+	}
+
+	return &mapping{ids}, nil
+}
+
+// NewFromFile loads a mapping from a text file. Each line of the file contains a source and a target
+// synset id separated by whitespace, e.g. "n02084071 n02086723". Empty lines and lines starting
+// with # are ignored.
+func NewFromFile(filename string) (Mapping, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	ids := map[string]string{}
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, errors.New(fmt.Sprintf("invalid mapping in line %d of %s", lineNumber, filename))
+		}
+		ids[fields[0]] = fields[1]
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return &mapping{ids}, nil
+}
+
+// readSenseIndex reads an index.sense file and returns a map from sense key to synset id.
+func readSenseIndex(filename string) (map[string]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	index := map[string]string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// sense_key synset_offset sense_number tag_cnt
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+
+		pos, err := posFromSenseKey(fields[0])
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid sense index %s", filename)
+		}
+
+		index[fields[0]] = pos + fields[1]
+	}
+
+	return index, scanner.Err()
+}
+
+// posFromSenseKey extracts the part of speech from a sense key. A sense key has the form
+// lemma%ss_type:lex_filenum:lex_id:head_word:head_id where ss_type is a number from 1 to 5.
+func posFromSenseKey(senseKey string) (string, error) {
+	idx := strings.Index(senseKey, "%")
+	if idx < 0 || idx+1 >= len(senseKey) {
+		return "", errors.New(fmt.Sprintf("malformed sense key %s", senseKey))
+	}
+
+	switch senseKey[idx+1] {
+	case '1':
+		return "n", nil
+	case '2':
+		return "v", nil
+	case '3':
+		return "a", nil
+	case '4':
+		return "r", nil
+	case '5':
+		return "s", nil
+	}
+
+	return "", errors.New(fmt.Sprintf("unknown synset type in sense key %s", senseKey))
+}
+
+// bestCandidate returns the synset id with the most votes. Ties are resolved by taking the
+// smallest id so that the mapping is deterministic.
+func bestCandidate(candidates map[string]int) string {
+	ids := make([]string, 0, len(candidates))
+	for id := range candidates {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool {
+		if candidates[ids[i]] != candidates[ids[j]] {
+			return candidates[ids[i]] > candidates[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+
+	return ids[0]
+}
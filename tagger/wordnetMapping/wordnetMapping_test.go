@@ -0,0 +1,92 @@
+package wordnetMapping
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func writeSenseIndex(t *testing.T, dir string, content string) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ioutil.WriteFile(path.Join(dir, senseIndexFile), []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewFromSenseIndex(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "wordnetMapping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	source := path.Join(tmp, "wn3.0")
+	target := path.Join(tmp, "wn3.1")
+
+	writeSenseIndex(t, source, `dog%1:05:00:: 02084071 1 42
+domestic_dog%1:05:00:: 02084071 1 0
+canis_familiaris%1:05:00:: 02084071 1 0
+run%2:38:00:: 01926311 1 105
+unicorn%1:18:00:: 09542934 1 0
+`)
+	writeSenseIndex(t, target, `dog%1:05:00:: 02086723 1 42
+domestic_dog%1:05:00:: 02086723 1 0
+canis_familiaris%1:05:00:: 02099999 1 0
+run%2:38:00:: 01928838 1 105
+`)
+
+	m, err := NewFromSenseIndex(source, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		source string
+		want   string
+		ok     bool
+	}{
+		{"n02084071", "n02086723", true},
+		{"v01926311", "v01928838", true},
+		{"n09542934", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := m.Map(tt.source)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Map(%s) = %s, %v, want %s, %v", tt.source, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestNewFromFile(t *testing.T) {
+	file, err := ioutil.TempFile("", "wordnetMapping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.WriteString("# wn3.0 wn3.1\nn02084071 n02086723\n\nn02121620\tn02123394\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	m, err := NewFromFile(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", m.Len())
+	}
+
+	if got, _ := m.Map("n02121620"); got != "n02123394" {
+		t.Errorf("Map(n02121620) = %s, want n02123394", got)
+	}
+}
@@ -0,0 +1,192 @@
+// The package wordnetSnapshot allows to store a parsed WordNet dictionary in a compact binary file.
+// Parsing the raw dictionary takes several seconds, while loading a snapshot is much faster.
+// A snapshot remembers a fingerprint of the dictionary it was compiled from, so outdated snapshots
+// can be detected and ignored.
+package wordnetSnapshot
+
+import (
+	"compress/gzip"
+	"crypto/sha1"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"github.com/fluhus/gostuff/nlp/wordnet"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+)
+
+// formatVersion has to be increased whenever the layout of Snapshot changes.
+const formatVersion = 3
+
+// ErrOutdated is returned by Load if the snapshot does not match the dictionary anymore.
+var ErrOutdated = errors.New("wordnet snapshot is outdated")
+
+type Snapshot struct {
+	Version int
+	// Fingerprint identifies the dictionary the snapshot was compiled from.
+	Fingerprint string
+	// WordNet contains the parsed synsets and the lemma index.
+	WordNet *wordnet.WordNet
+	// Hypernyms maps each synset id to the ids of all of its direct and indirect hypernyms.
+	Hypernyms map[string][]string
+}
+
+// Compile parses the dictionary in dictPath and computes the hypernym closure for all synsets.
+func Compile(dictPath string) (*Snapshot, error) {
+	fingerprint, err := Fingerprint(dictPath)
+	if err != nil {
+		return nil, err
+	}
+
+	wn, err := wordnet.Parse(dictPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Snapshot{
+		Version:     formatVersion,
+		Fingerprint: fingerprint,
+		WordNet:     wn,
+		Hypernyms:   hypernymClosures(wn),
+	}, nil
+}
+
+// Write stores the snapshot as gzip compressed gob in the given file. The file is replaced
+// only after the snapshot was written completely.
+func (s *Snapshot) Write(filename string) error {
+	tmpFile, err := ioutil.TempFile(path.Dir(filename), path.Base(filename))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	zw := gzip.NewWriter(tmpFile)
+	err = gob.NewEncoder(zw).Encode(s)
+	if err != nil {
+		tmpFile.Close()
+		return errors.Wrap(err, "could not encode wordnet snapshot")
+	}
+
+	err = zw.Close()
+	if err != nil {
+		tmpFile.Close()
+		return err
+	}
+
+	err = tmpFile.Close()
+	if err != nil {
+		return err
+	}
+
+	// temporary files are only readable by the owner, the snapshot is read like the dictionary
+	err = os.Chmod(tmpFile.Name(), 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmpFile.Name(), filename)
+}
+
+// Load reads a snapshot from the given file. If the snapshot was written by a different version
+// of imtag or the dictionary in dictPath has changed since compilation ErrOutdated is returned.
+func Load(filename, dictPath string) (*Snapshot, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read wordnet snapshot")
+	}
+	defer zr.Close()
+
+	snapshot := &Snapshot{}
+	err = gob.NewDecoder(zr).Decode(snapshot)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not decode wordnet snapshot")
+	}
+
+	if snapshot.Version != formatVersion {
+		return nil, ErrOutdated
+	}
+
+	fingerprint, err := Fingerprint(dictPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if snapshot.Fingerprint != fingerprint {
+		return nil, ErrOutdated
+	}
+
+	return snapshot, nil
+}
+
+// Fingerprint computes a hash over names, sizes and modification times of the files in the
+// dictionary directory. Reading the whole dictionary would take almost as long as parsing it.
+func Fingerprint(dictPath string) (string, error) {
+	files, err := ioutil.ReadDir(dictPath)
+	if err != nil {
+		return "", err
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name() < files[j].Name()
+	})
+
+	hash := sha1.New()
+	for _, f := range files {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+		_, _ = fmt.Fprintf(hash, "%s %d %d\n", f.Name(), f.Size(), f.ModTime().UnixNano())
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// hypernymClosures computes all ancestors of each synset in the hierarchy.
+func hypernymClosures(wn *wordnet.WordNet) map[string][]string {
+	closures := make(map[string][]string, len(wn.Synset))
+	for id, synset := range wn.Synset {
+		if hypernyms := HypernymClosure(wn, synset); len(hypernyms) > 0 {
+			closures[id] = hypernyms
+		}
+	}
+	return closures
+}
+
+// HypernymClosure returns the ids of all direct and indirect hypernyms of a synset, the nearest
+// hypernyms come first.
+func HypernymClosure(wn *wordnet.WordNet, synset *wordnet.Synset) []string {
+	var hypernyms []string
+	visited := map[string]bool{}
+	synsetsToSearch := []*wordnet.Synset{synset}
+
+	for len(synsetsToSearch) > 0 {
+		current := synsetsToSearch[0]
+		synsetsToSearch = synsetsToSearch[1:]
+
+		for _, p := range current.Pointer {
+			if p.Symbol != wordnet.Hypernym || visited[p.Synset] {
+				continue
+			}
+
+			hypernym, ok := wn.Synset[p.Synset]
+			if !ok {
+				continue
+			}
+
+			visited[p.Synset] = true
+			hypernyms = append(hypernyms, p.Synset)
+			synsetsToSearch = append(synsetsToSearch, hypernym)
+		}
+	}
+
+	return hypernyms
+}
@@ -0,0 +1,93 @@
+package wordnetSnapshot
+
+import (
+	"github.com/fluhus/gostuff/nlp/wordnet"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestSnapshot_WriteAndLoad(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "wordnetSnapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	dictPath := path.Join(tmp, "dict")
+	err = os.Mkdir(dictPath, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(path.Join(dictPath, "data.noun"), []byte("dummy"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fingerprint, err := Fingerprint(dictPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wn := &wordnet.WordNet{
+		Synset: map[string]*wordnet.Synset{
+			"n00000001": {Offset: "00000001", Pos: "n", Word: []string{"entity"}},
+			"n00000002": {Offset: "00000002", Pos: "n", Word: []string{"animal"},
+				Pointer: []*wordnet.Pointer{{Symbol: wordnet.Hypernym, Synset: "n00000001", Source: -1, Target: -1}}},
+			"n00000003": {Offset: "00000003", Pos: "n", Word: []string{"cat"},
+				Pointer: []*wordnet.Pointer{{Symbol: wordnet.Hypernym, Synset: "n00000002", Source: -1, Target: -1}}},
+		},
+	}
+
+	snapshot := &Snapshot{
+		Version:     formatVersion,
+		Fingerprint: fingerprint,
+		WordNet:     wn,
+		Hypernyms:   hypernymClosures(wn),
+	}
+
+	hypernyms := append([]string{}, snapshot.Hypernyms["n00000003"]...)
+	sort.Strings(hypernyms)
+	if !reflect.DeepEqual(hypernyms, []string{"n00000001", "n00000002"}) {
+		t.Errorf("hypernymClosures() = %v, want [n00000001 n00000002]", hypernyms)
+	}
+
+	snapshotFile := path.Join(tmp, "dict.snapshot")
+	err = snapshot.Write(snapshotFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fi, err := os.Stat(snapshotFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0644 {
+		t.Errorf("snapshot mode = %v, want %v", fi.Mode().Perm(), os.FileMode(0644))
+	}
+
+	loaded, err := Load(snapshotFile, dictPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.WordNet.Synset["n00000003"], wn.Synset["n00000003"]) {
+		t.Errorf("Load() synset = %v, want %v", loaded.WordNet.Synset["n00000003"], wn.Synset["n00000003"])
+	}
+	if !reflect.DeepEqual(loaded.Hypernyms, snapshot.Hypernyms) {
+		t.Errorf("Load() hypernyms = %v, want %v", loaded.Hypernyms, snapshot.Hypernyms)
+	}
+
+	// changing the dictionary must invalidate the snapshot
+	err = ioutil.WriteFile(path.Join(dictPath, "data.verb"), []byte("dummy"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Load(snapshotFile, dictPath)
+	if err != ErrOutdated {
+		t.Errorf("Load() error = %v, want %v", err, ErrOutdated)
+	}
+}
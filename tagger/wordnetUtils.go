@@ -1,60 +1,57 @@
 package tagger
 
 import (
-	"bufio"
 	"github.com/fluhus/gostuff/nlp/wordnet"
-	"os"
+	"github.com/twatzl/imtag/tagger/tag"
+	"github.com/twatzl/imtag/tagger/wordnetSnapshot"
 	"sort"
+	"sync"
 )
 
-func loadSynsetsForClassifierLabels(wn *wordnet.WordNet, path string) (synsets []*wordnet.Synset, err error) {
-	file, err := os.Open(path)
-	if err != nil {
-		// TODO error
-		return nil, err
-	}
-	defer file.Close()
-
-	synsets = []*wordnet.Synset{}
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		synsetId := scanner.Text()
-		synset := wn.Synset[synsetId]
-		if synset == nil {
-			//todo write warting
-			continue
-		}
-		synsets = append(synsets, synset)
+// hypernymClosures holds the precomputed hypernyms of the dictionaries loaded from a snapshot.
+var (
+	hypernymClosuresMutex sync.RWMutex
+	hypernymClosures      = map[*wordnet.WordNet]map[string][]string{}
+)
+
+// SetHypernymClosures registers the hypernym closures of a WordNet snapshot for its dictionary, so
+// the ancestors of a synset are looked up instead of searched in the hierarchy.
+func SetHypernymClosures(wn *wordnet.WordNet, closures map[string][]string) {
+	hypernymClosuresMutex.Lock()
+	defer hypernymClosuresMutex.Unlock()
+	hypernymClosures[wn] = closures
+}
+
+// ancestorIds returns the ids of all direct and indirect hypernyms of the synset. The closures of the
+// snapshot are used if they were registered, otherwise the hierarchy is searched.
+func ancestorIds(wn *wordnet.WordNet, synset *wordnet.Synset) []string {
+	hypernymClosuresMutex.RLock()
+	closures, ok := hypernymClosures[wn]
+	hypernymClosuresMutex.RUnlock()
+
+	if ok {
+		return closures[synset.Id()]
 	}
+	return wordnetSnapshot.HypernymClosure(wn, synset)
+}
 
-	return synsets, scanner.Err()
+// synsetForTag returns the synset of a tag of the classifier or nil if the tag has no synset id or
+// the id is not part of the dictionary. The synset ids are loaded by the classifiers from the wnid
+// label file of their config.
+func synsetForTag(wn *wordnet.WordNet, t tag.Tag) *wordnet.Synset {
+	if wn == nil || t.GetSynsetId() == "" {
+		return nil
+	}
+	return wn.Synset[t.GetSynsetId()]
 }
 
 func loadAncestorLabelsForSynset(wn *wordnet.WordNet, synset *wordnet.Synset) []string {
 	// map because wordnet allows duplicates
 	hypernyms := map[string]interface{}{}
 
-	synsetsToSearch := []*wordnet.Synset{synset}
-
-	for len(synsetsToSearch) > 0 {
-		// deque next element from synsets
-		synset = synsetsToSearch[0]
-		synsetsToSearch = synsetsToSearch[1:]
-
-		for _, p := range synset.Pointer {
-			if p.Symbol == wordnet.Hypernym {
-				synsetId := p.Synset
-				hypernymSynset := wn.Synset[synsetId]
-				synsetsToSearch = append(synsetsToSearch, hypernymSynset)
-
-				word := hypernymSynset.Word[0]
-				if p.Target != -1 {
-					word = hypernymSynset.Word[p.Target]
-				}
-
-				hypernyms[word] = ""
-			}
-		}
+	// hypernym pointers link whole synsets, so the first word is the label of a hypernym
+	for _, synsetId := range ancestorIds(wn, synset) {
+		hypernyms[wn.Synset[synsetId].Word[0]] = ""
 	}
 
 	hypernymSlice := make([]string, 0)
@@ -124,4 +121,45 @@ func loadAncestorHierarchyForSynset(wn *wordnet.WordNet, synset *wordnet.Synset)
 	})
 
 	return hypernyms
-}
\ No newline at end of file
+}
+
+// instanceHypernym is the pointer symbol which links instances like people or places to their class,
+// e.g. Einstein to physicist.
+const instanceHypernym = "@i"
+
+// HypernymPath returns the chain of hypernyms from the given synset up to the root of the
+// hierarchy (usually entity). If a synset has multiple hypernyms the first one is followed.
+// Instances have no hypernym, for them the first instance hypernym is followed instead.
+// The synset itself is the first element of the path.
+func HypernymPath(wn *wordnet.WordNet, synset *wordnet.Synset) []*wordnet.Synset {
+	path := []*wordnet.Synset{synset}
+	visited := map[string]bool{synset.Id(): true}
+
+	for {
+		next := nextHypernym(wn, synset, wordnet.Hypernym, visited)
+		if next == nil {
+			next = nextHypernym(wn, synset, instanceHypernym, visited)
+		}
+
+		if next == nil {
+			return path
+		}
+
+		visited[next.Id()] = true
+		path = append(path, next)
+		synset = next
+	}
+}
+
+// nextHypernym returns the first synset which is linked by a pointer with the given symbol and
+// was not visited yet.
+func nextHypernym(wn *wordnet.WordNet, synset *wordnet.Synset, symbol string, visited map[string]bool) *wordnet.Synset {
+	for _, p := range synset.Pointer {
+		if p.Symbol == symbol && !visited[p.Synset] {
+			if next, ok := wn.Synset[p.Synset]; ok {
+				return next
+			}
+		}
+	}
+	return nil
+}
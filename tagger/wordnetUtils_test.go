@@ -106,3 +106,50 @@ func Test_loadAncestorHierarchyForSynset(t *testing.T) {
 		})
 	}
 }
+
+func TestHypernymPath(t *testing.T) {
+	wn := &wordnet.WordNet{
+		Synset: map[string]*wordnet.Synset{
+			"n00000001": {Offset: "00000001", Pos: "n", Word: []string{"entity"}},
+			"n00000002": {Offset: "00000002", Pos: "n", Word: []string{"physicist"},
+				Pointer: []*wordnet.Pointer{{Symbol: wordnet.Hypernym, Synset: "n00000001"}}},
+			"n00000003": {Offset: "00000003", Pos: "n", Word: []string{"Einstein"},
+				Pointer: []*wordnet.Pointer{{Symbol: instanceHypernym, Synset: "n00000002"}}},
+		},
+	}
+
+	got := []string{}
+	for _, s := range HypernymPath(wn, wn.Synset["n00000003"]) {
+		got = append(got, s.Id())
+	}
+
+	want := []string{"n00000003", "n00000002", "n00000001"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("HypernymPath() = %v, want %v", got, want)
+	}
+}
+
+func TestLoadAncestorLabelsForSynset_Closures(t *testing.T) {
+	wn := &wordnet.WordNet{
+		Synset: map[string]*wordnet.Synset{
+			"n00000001": {Offset: "00000001", Pos: "n", Word: []string{"entity"}},
+			"n00000002": {Offset: "00000002", Pos: "n", Word: []string{"animal"},
+				Pointer: []*wordnet.Pointer{{Symbol: wordnet.Hypernym, Synset: "n00000001", Source: -1, Target: -1}}},
+			"n00000003": {Offset: "00000003", Pos: "n", Word: []string{"cat"},
+				Pointer: []*wordnet.Pointer{{Symbol: wordnet.Hypernym, Synset: "n00000002", Source: -1, Target: -1}}},
+		},
+	}
+
+	got := loadAncestorLabelsForSynset(wn, wn.Synset["n00000003"])
+	sort.Strings(got)
+	if want := []string{"animal", "entity"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("loadAncestorLabelsForSynset() = %v, want %v", got, want)
+	}
+
+	// the registered closures are used instead of the hierarchy
+	SetHypernymClosures(wn, map[string][]string{"n00000003": {"n00000002"}})
+	got = loadAncestorLabelsForSynset(wn, wn.Synset["n00000003"])
+	if want := []string{"animal"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("loadAncestorLabelsForSynset() with closures = %v, want %v", got, want)
+	}
+}